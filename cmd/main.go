@@ -7,14 +7,16 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // time zones ของ schedule ไม่ต้องพึ่ง zoneinfo ของเครื่อง
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/parinyadagon/go-workflow/config"
-	"github.com/parinyadagon/go-workflow/db"
+	database "github.com/parinyadagon/go-workflow/db"
 	repository "github.com/parinyadagon/go-workflow/internal/adapters/driven"
 	handler "github.com/parinyadagon/go-workflow/internal/adapters/driving"
 	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/internal/core/scheduler"
 	"github.com/parinyadagon/go-workflow/internal/core/service"
 	"github.com/parinyadagon/go-workflow/internal/core/worker"
 	"github.com/parinyadagon/go-workflow/internal/workflows/order"
@@ -34,12 +36,18 @@ func main() {
 
 	logger.Info().Str("environment", cfg.Environment).Msg("Starting application")
 
-	db, err := db.NewConnection(&cfg.Database)
+	db, err := database.NewConnection(&cfg.Database)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to connect to database")
 	}
 	defer db.Close()
 
+	migrated, err := database.Migrate(context.Background(), db)
+	if err != nil {
+		logger.Fatal().Err(err).Strs("applied", migrated).Msg("Failed to migrate database")
+	}
+	logger.Info().Strs("applied", migrated).Msg("Database schema is up to date")
+
 	// สร้าง registry และ register workflows
 	workflowRegistry := registry.NewWorkflowRegistry()
 
@@ -47,17 +55,34 @@ func main() {
 	order.Register(workflowRegistry)
 	refund.Register(workflowRegistry)
 
+	// Declarative workflows ใช้ activity ที่ register ไว้ข้างบน
+	loaded, err := workflowRegistry.LoadDefinitions(cfg.WorkflowDefinitionsDir)
+	if err != nil {
+		logger.Fatal().Err(err).Str("dir", cfg.WorkflowDefinitionsDir).Msg("Failed to load workflow definitions")
+	}
+	logger.Info().Strs("workflows", loaded).Str("dir", cfg.WorkflowDefinitionsDir).Msg("Loaded workflow definitions")
+
 	repo := repository.NewWorkflowRepository(db)
+	schedules := repository.NewScheduleRepository(db)
 	svc := service.NewWorkflowService(repo, workflowRegistry)
 	hdl := handler.NewWorkflowHandler(svc)
+	scheduleSvc := service.NewScheduleService(schedules, workflowRegistry)
+	scheduleHdl := handler.NewScheduleHandler(scheduleSvc)
 
-	workerNode := worker.NewWorkflowWorker(repo, workflowRegistry, &cfg.Worker)
+	workerNode, err := worker.NewWorkflowWorker(repo, workflowRegistry, svc, &cfg.Worker)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create worker")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	go workerNode.Start(ctx)
 
+	// ทุก replica รัน scheduler ได้ แต่ละรอบจะ fire แค่ครั้งเดียว
+	scheduleNode := scheduler.NewScheduler(repo, schedules, svc, &cfg.Scheduler)
+	go scheduleNode.Start(ctx)
+
 	e := echo.New()
 
 	// CORS middleware
@@ -89,11 +114,27 @@ func main() {
 		})
 	})
 
+	// Worker pool utilisation
+	e.GET("/worker/stats", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, workerNode.Stats())
+	})
+
 	// Workflow endpoints
 	e.GET("/workflows/available", hdl.ListAvailableWorkflows)
 	e.GET("/workflows", hdl.ListWorkflows)
 	e.POST("/workflows", hdl.StartWorkflow)
 	e.GET("/workflows/:id", hdl.GetWorkflowDetail)
+	e.POST("/workflows/:id/cancel", hdl.CancelWorkflow)
+	e.POST("/workflows/:id/signals/:name", hdl.SendSignal)
+
+	// Schedule endpoints
+	e.GET("/schedules", scheduleHdl.ListSchedules)
+	e.POST("/schedules", scheduleHdl.CreateSchedule)
+	e.GET("/schedules/:id", scheduleHdl.GetSchedule)
+	e.PUT("/schedules/:id", scheduleHdl.UpdateSchedule)
+	e.DELETE("/schedules/:id", scheduleHdl.DeleteSchedule)
+	e.POST("/schedules/:id/pause", scheduleHdl.PauseSchedule)
+	e.POST("/schedules/:id/resume", scheduleHdl.ResumeSchedule)
 
 	// 4. Start Server
 	go func() {
@@ -116,6 +157,14 @@ func main() {
 		logger.Fatal().Err(err).Msg("Server shutdown failed")
 	}
 
+	// Stop claiming tasks and let running ones finish (or release them)
+	ctxDrain, cancelDrain := context.WithTimeout(context.Background(), cfg.Worker.ShutdownGrace+5*time.Second)
+	defer cancelDrain()
+
+	if err := workerNode.Shutdown(ctxDrain); err != nil {
+		logger.Error().Err(err).Msg("Worker shutdown failed")
+	}
+
 	logger.Info().Msg("Server exited")
 
 }
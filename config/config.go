@@ -26,17 +26,32 @@ type ServerConfig struct {
 }
 
 type WorkerConfig struct {
+	WorkerID          string
+	PollInterval      time.Duration
+	BatchSize         int
+	MaxConcurrency    int
+	TaskTimeout       time.Duration
+	MaxRetries        int
+	LeaseDuration     time.Duration
+	HeartbeatInterval time.Duration
+	ReaperInterval    time.Duration
+	ShutdownGrace     time.Duration
+}
+
+// SchedulerConfig controls how often cron schedules are checked for due runs
+type SchedulerConfig struct {
 	PollInterval time.Duration
 	BatchSize    int
-	TaskTimeout  time.Duration
-	MaxRetries   int
 }
 
 type Config struct {
 	Database    DatabaseConfig
 	Server      ServerConfig
 	Worker      WorkerConfig
+	Scheduler   SchedulerConfig
 	Environment string
+	// WorkflowDefinitionsDir holds declarative workflow files (YAML/JSON)
+	WorkflowDefinitionsDir string
 }
 
 func Load() (*Config, error) {
@@ -60,12 +75,23 @@ func Load() (*Config, error) {
 			Port: getEnvAsInt("SERVER_PORT", 8080),
 		},
 		Worker: WorkerConfig{
-			PollInterval: getEnvAsDuration("WORKER_POLL_INTERVAL", 5*time.Second),
-			BatchSize:    getEnvAsInt("WORKER_BATCH_SIZE", 10),
-			TaskTimeout:  getEnvAsDuration("WORKER_TASK_TIMEOUT", 30*time.Second),
-			MaxRetries:   getEnvAsInt("WORKER_MAX_RETRIES", 3),
+			WorkerID:          getEnv("WORKER_ID", defaultWorkerID()),
+			PollInterval:      getEnvAsDuration("WORKER_POLL_INTERVAL", 5*time.Second),
+			BatchSize:         getEnvAsInt("WORKER_BATCH_SIZE", 10),
+			MaxConcurrency:    getEnvAsInt("WORKER_MAX_CONCURRENCY", 10),
+			TaskTimeout:       getEnvAsDuration("WORKER_TASK_TIMEOUT", 30*time.Second),
+			MaxRetries:        getEnvAsInt("WORKER_MAX_RETRIES", 3),
+			LeaseDuration:     getEnvAsDuration("WORKER_LEASE_DURATION", 30*time.Second),
+			HeartbeatInterval: getEnvAsDuration("WORKER_HEARTBEAT_INTERVAL", 10*time.Second),
+			ReaperInterval:    getEnvAsDuration("WORKER_REAPER_INTERVAL", 15*time.Second),
+			ShutdownGrace:     getEnvAsDuration("WORKER_SHUTDOWN_GRACE", 25*time.Second),
 		},
-		Environment: getEnv("ENV", "development"),
+		Scheduler: SchedulerConfig{
+			PollInterval: getEnvAsDuration("SCHEDULER_POLL_INTERVAL", 10*time.Second),
+			BatchSize:    getEnvAsInt("SCHEDULER_BATCH_SIZE", 50),
+		},
+		Environment:            getEnv("ENV", "development"),
+		WorkflowDefinitionsDir: getEnv("WORKFLOW_DEFINITIONS_DIR", "definitions"),
 	}
 
 	return config, nil
@@ -101,6 +127,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// defaultWorkerID identifies this process when claiming tasks (hostname-pid)
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "worker"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
 func getEnvRequired(key string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -0,0 +1,29 @@
+package config
+
+import "testing"
+
+func TestLoadWorkerMaxConcurrency(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"from environment", "7", 7},
+		{"default", "", 10},
+		{"not a number", "many", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WORKER_MAX_CONCURRENCY", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Worker.MaxConcurrency != tt.want {
+				t.Errorf("Worker.MaxConcurrency = %d, want %d", cfg.Worker.MaxConcurrency, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock ทำให้ replica ที่ start พร้อมกันรัน migration ทีละตัว
+const migrationLock = "go_flow_schema_migrations"
+
+type migration struct {
+	version    string
+	statements []string
+}
+
+// Migrate applies the files in db/migrations that are not recorded in
+// schema_migrations yet, in file name order, and returns their versions
+func Migrate(ctx context.Context, database *sql.DB) ([]string, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// GET_LOCK belongs to a session, so every statement runs on one connection
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", migrationLock).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	if locked.Int64 != 1 {
+		return nil, fmt.Errorf("timed out waiting for migration lock %s", migrationLock)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(?)", migrationLock)
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		// DDL commits implicitly in MySQL: a file that fails halfway is not
+		// rolled back and must be finished by hand before retrying
+		for i, stmt := range m.statements {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return versions, fmt.Errorf("migration %s statement %d: %w", m.version, i+1, err)
+			}
+		}
+		if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", m.version); err != nil {
+			return versions, fmt.Errorf("failed to record migration %s: %w", m.version, err)
+		}
+		versions = append(versions, m.version)
+	}
+
+	return versions, nil
+}
+
+// appliedMigrations returns nothing until 0001 has created schema_migrations
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	var exists int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_migrations'",
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+
+	applied := make(map[string]bool)
+	if exists == 0 {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+func loadMigrations() ([]migration, error) {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	migrations := make([]migration, 0, len(names))
+	for _, name := range names {
+		content, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		statements := splitStatements(string(content))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("migration %s has no statements", name)
+		}
+		migrations = append(migrations, migration{
+			version:    strings.TrimSuffix(path.Base(name), ".sql"),
+			statements: statements,
+		})
+	}
+
+	return migrations, nil
+}
+
+// splitStatements splits a file on lines ending with ';' and drops '--'
+// comment lines; the driver runs one statement per Exec
+func splitStatements(content string) []string {
+	var statements []string
+	var current []string
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current = append(current, line)
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSpace(strings.Join(current, "\n"))
+			statements = append(statements, strings.TrimSuffix(stmt, ";"))
+			current = nil
+		}
+	}
+
+	if len(current) > 0 {
+		statements = append(statements, strings.TrimSpace(strings.Join(current, "\n")))
+	}
+
+	return statements
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	content := `-- header comment
+
+CREATE TABLE a (
+    id INT -- trailing comments stay
+);
+
+UPDATE a SET id = 1;
+-- between statements
+ALTER TABLE a ADD COLUMN b INT`
+
+	want := []string{
+		"CREATE TABLE a (\n    id INT -- trailing comments stay\n)",
+		"UPDATE a SET id = 1",
+		"ALTER TABLE a ADD COLUMN b INT",
+	}
+	if got := splitStatements(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitStatements = %q, want %q", got, want)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) < 2 || migrations[0].version != "0001_schema_migrations" {
+		t.Fatalf("migrations start with %v, want 0001_schema_migrations", migrations)
+	}
+
+	// Numbers are consecutive so two branches cannot add the same one unnoticed
+	for i, m := range migrations {
+		if prefix := fmt.Sprintf("%04d_", i+1); len(m.version) <= len(prefix) || m.version[:len(prefix)] != prefix {
+			t.Errorf("migration %d is %s, want prefix %s", i, m.version, prefix)
+		}
+	}
+}
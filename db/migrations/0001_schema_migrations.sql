@@ -0,0 +1,6 @@
+-- Versions applied by db.Migrate, one row per file in this directory
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,36 @@
+-- Original schema. IF NOT EXISTS lets a deployment created from it before
+-- migrations existed continue with 0003
+
+CREATE TABLE IF NOT EXISTS workflow_instances (
+    id VARCHAR(36) PRIMARY KEY,
+    workflow_name VARCHAR(255) NOT NULL,
+    status ENUM('PENDING', 'RUNNING', 'COMPLETED', 'FAILED') DEFAULT 'PENDING',
+    current_input JSON,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    workflow_instance_id VARCHAR(36) NOT NULL,
+    task_name VARCHAR(255) NOT NULL,
+    status ENUM('PENDING', 'RUNNING', 'COMPLETED', 'FAILED', 'RETRYING') DEFAULT 'PENDING',
+    retry_count INT DEFAULT 0,
+    input_payload JSON,
+    output_payload JSON,
+    error_message TEXT,
+    scheduled_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (workflow_instance_id) REFERENCES workflow_instances(id)
+);
+
+CREATE TABLE IF NOT EXISTS activity_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    workflow_instance_id VARCHAR(36) NOT NULL,
+    task_name VARCHAR(255),
+    event_type VARCHAR(100) NOT NULL,
+    details JSON,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (workflow_instance_id) REFERENCES workflow_instances(id)
+);
@@ -0,0 +1,19 @@
+-- Tasks are claimed atomically by one worker. IN_PROGRESS replaces RUNNING
+-- (gen/go_flow was generated with it); RUNNING stays in the enum until the
+-- existing rows are moved
+
+ALTER TABLE tasks
+    MODIFY status ENUM('PENDING', 'IN_PROGRESS', 'COMPLETED', 'FAILED', 'RETRYING', 'RUNNING') DEFAULT 'PENDING';
+
+UPDATE tasks SET status = 'IN_PROGRESS' WHERE status = 'RUNNING';
+
+ALTER TABLE tasks
+    MODIFY status ENUM('PENDING', 'IN_PROGRESS', 'COMPLETED', 'FAILED', 'RETRYING') DEFAULT 'PENDING';
+
+ALTER TABLE tasks
+    ADD COLUMN claimed_by VARCHAR(64) NULL AFTER scheduled_at,
+    ADD COLUMN claimed_at TIMESTAMP NULL AFTER claimed_by,
+    ADD INDEX idx_tasks_status (status);
+
+-- Tasks picked up by the old worker have no owner; put them back in the queue
+UPDATE tasks SET status = 'PENDING' WHERE status IN ('IN_PROGRESS', 'RETRYING') AND claimed_by IS NULL;
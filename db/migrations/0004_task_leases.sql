@@ -0,0 +1,9 @@
+-- Claims expire unless the worker's heartbeat renews them
+
+ALTER TABLE tasks
+    ADD COLUMN lease_expires_at TIMESTAMP NULL AFTER claimed_at;
+
+-- Claims taken before leases existed are never renewed; let the reaper
+-- return them to the queue
+UPDATE tasks SET lease_expires_at = CURRENT_TIMESTAMP
+WHERE status IN ('IN_PROGRESS', 'RETRYING') AND lease_expires_at IS NULL;
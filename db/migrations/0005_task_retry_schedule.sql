@@ -0,0 +1,5 @@
+-- Retries wait in PENDING until scheduled_at; the claim query filters on both
+
+ALTER TABLE tasks
+    DROP INDEX idx_tasks_status,
+    ADD INDEX idx_tasks_status_scheduled (status, scheduled_at);
@@ -0,0 +1,4 @@
+-- Overall workflow timeout, set when the instance is started
+
+ALTER TABLE workflow_instances
+    ADD COLUMN deadline_at TIMESTAMP NULL AFTER current_input;
@@ -0,0 +1,4 @@
+-- Output of the final task, returned as the workflow result
+
+ALTER TABLE workflow_instances
+    ADD COLUMN current_output JSON AFTER current_input;
@@ -0,0 +1,14 @@
+-- Each step is created once per instance. The old worker could create a step
+-- twice; keep one row per (workflow_instance_id, task_name): a COMPLETED one
+-- if there is one, otherwise the oldest
+
+DELETE t1 FROM tasks t1
+JOIN tasks t2
+    ON t2.workflow_instance_id = t1.workflow_instance_id
+    AND t2.task_name = t1.task_name
+    AND t2.id <> t1.id
+WHERE (COALESCE(t2.status, '') = 'COMPLETED') > (COALESCE(t1.status, '') = 'COMPLETED')
+    OR ((COALESCE(t2.status, '') = 'COMPLETED') = (COALESCE(t1.status, '') = 'COMPLETED') AND t2.id < t1.id);
+
+ALTER TABLE tasks
+    ADD UNIQUE KEY uq_tasks_workflow_task (workflow_instance_id, task_name);
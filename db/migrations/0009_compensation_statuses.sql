@@ -0,0 +1,4 @@
+-- Saga compensation of permanently failed workflows
+
+ALTER TABLE workflow_instances
+    MODIFY status ENUM('PENDING', 'RUNNING', 'COMPLETED', 'FAILED', 'COMPENSATING', 'COMPENSATED') DEFAULT 'PENDING';
@@ -0,0 +1,10 @@
+-- Child workflow steps wait for the child; cancelling a parent cancels it
+
+ALTER TABLE tasks
+    MODIFY status ENUM('PENDING', 'IN_PROGRESS', 'COMPLETED', 'FAILED', 'RETRYING', 'WAITING', 'CANCELLED') DEFAULT 'PENDING';
+
+ALTER TABLE workflow_instances
+    MODIFY status ENUM('PENDING', 'RUNNING', 'COMPLETED', 'FAILED', 'COMPENSATING', 'COMPENSATED', 'CANCELLED') DEFAULT 'PENDING',
+    ADD COLUMN parent_id VARCHAR(36) NULL AFTER deadline_at,
+    ADD COLUMN parent_task_id INT NULL AFTER parent_id,
+    ADD INDEX idx_workflow_instances_parent (parent_id);
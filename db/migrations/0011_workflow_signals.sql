@@ -0,0 +1,16 @@
+-- Signals sent to a workflow, and the deadline of a step waiting for one
+
+CREATE TABLE workflow_signals (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workflow_instance_id VARCHAR(36) NOT NULL,
+    signal_name VARCHAR(255) NOT NULL,
+    payload JSON,
+    consumed_by_task_id INT NULL,
+    consumed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_workflow_signals_name (workflow_instance_id, signal_name),
+    FOREIGN KEY (workflow_instance_id) REFERENCES workflow_instances(id)
+);
+
+ALTER TABLE tasks
+    ADD COLUMN wait_until TIMESTAMP NULL AFTER lease_expires_at;
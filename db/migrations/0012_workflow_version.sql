@@ -0,0 +1,5 @@
+-- Instances are pinned to the definition version they started with; existing
+-- instances run version 1
+
+ALTER TABLE workflow_instances
+    ADD COLUMN workflow_version INT NOT NULL DEFAULT 1 AFTER workflow_name;
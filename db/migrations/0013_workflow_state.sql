@@ -0,0 +1,4 @@
+-- Workflow input plus the output of every completed step
+
+ALTER TABLE workflow_instances
+    ADD COLUMN state JSON AFTER current_output;
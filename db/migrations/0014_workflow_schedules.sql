@@ -0,0 +1,20 @@
+-- Cron schedules that start workflows
+
+CREATE TABLE workflow_schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    workflow_name VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(255) NOT NULL,
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    input_payload JSON,
+    overlap_policy ENUM('SKIP', 'ALLOW', 'BUFFER_ONE') DEFAULT 'SKIP',
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    buffered BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP NULL,
+    last_workflow_id VARCHAR(36) NULL,
+    last_error TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_workflow_schedules_due (paused, next_run_at)
+);
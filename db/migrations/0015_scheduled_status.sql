@@ -0,0 +1,4 @@
+-- Instances started with start_at or delay wait in SCHEDULED
+
+ALTER TABLE workflow_instances
+    MODIFY status ENUM('PENDING', 'RUNNING', 'COMPLETED', 'FAILED', 'COMPENSATING', 'COMPENSATED', 'CANCELLED', 'SCHEDULED') DEFAULT 'PENDING';
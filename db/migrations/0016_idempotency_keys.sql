@@ -0,0 +1,6 @@
+-- A client retry with the same Idempotency-Key returns the original instance
+
+ALTER TABLE workflow_instances
+    ADD COLUMN idempotency_key VARCHAR(255) NULL AFTER parent_task_id,
+    ADD COLUMN request_hash CHAR(64) NULL AFTER idempotency_key,
+    ADD UNIQUE KEY uq_workflow_instances_idempotency_key (idempotency_key);
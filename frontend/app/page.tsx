@@ -243,6 +243,8 @@ export default function Home() {
                             ? "bg-green-100 dark:bg-green-500/10 text-green-800 dark:text-green-400 border border-green-500"
                             : workflow.Status === "FAILED"
                             ? "bg-red-100 dark:bg-red-500/10 text-red-800 dark:text-red-400 border border-red-500"
+                            : workflow.Status === "COMPENSATING" || workflow.Status === "COMPENSATED"
+                            ? "bg-orange-100 dark:bg-orange-500/10 text-orange-800 dark:text-orange-400 border border-orange-500"
                             : workflow.Status === "RUNNING"
                             ? "bg-yellow-100 dark:bg-yellow-400/10 text-yellow-800 dark:text-yellow-400 border border-yellow-400"
                             : "bg-gray-100 dark:bg-slate-600/50 text-gray-800 dark:text-slate-400 border border-gray-300 dark:border-slate-600"
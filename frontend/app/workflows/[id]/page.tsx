@@ -383,6 +383,9 @@ function getEventTypeBadge(eventType: string) {
       return "bg-purple-100 text-purple-700 dark:bg-purple-500/20 dark:text-purple-400";
     case "TASK_FAILED":
       return "bg-red-100 text-red-700 dark:bg-red-500/20 dark:text-red-400";
+    case "COMPENSATION_STARTED":
+    case "COMPENSATION_COMPLETED":
+      return "bg-orange-100 text-orange-700 dark:bg-orange-500/20 dark:text-orange-400";
     default:
       return "bg-slate-100 text-slate-600 dark:bg-slate-700 dark:text-slate-400";
   }
@@ -15,10 +15,14 @@ var TasksStatus = &struct {
 	Completed  mysql.StringExpression
 	Failed     mysql.StringExpression
 	Retrying   mysql.StringExpression
+	Waiting    mysql.StringExpression
+	Cancelled  mysql.StringExpression
 }{
 	Pending:    mysql.NewEnumValue("PENDING"),
 	InProgress: mysql.NewEnumValue("IN_PROGRESS"),
 	Completed:  mysql.NewEnumValue("COMPLETED"),
 	Failed:     mysql.NewEnumValue("FAILED"),
 	Retrying:   mysql.NewEnumValue("RETRYING"),
+	Waiting:    mysql.NewEnumValue("WAITING"),
+	Cancelled:  mysql.NewEnumValue("CANCELLED"),
 }
@@ -10,13 +10,21 @@ package enum
 import "github.com/go-jet/jet/v2/mysql"
 
 var WorkflowInstancesStatus = &struct {
-	Pending   mysql.StringExpression
-	Running   mysql.StringExpression
-	Completed mysql.StringExpression
-	Failed    mysql.StringExpression
+	Pending      mysql.StringExpression
+	Running      mysql.StringExpression
+	Completed    mysql.StringExpression
+	Failed       mysql.StringExpression
+	Compensating mysql.StringExpression
+	Compensated  mysql.StringExpression
+	Cancelled    mysql.StringExpression
+	Scheduled    mysql.StringExpression
 }{
-	Pending:   mysql.NewEnumValue("PENDING"),
-	Running:   mysql.NewEnumValue("RUNNING"),
-	Completed: mysql.NewEnumValue("COMPLETED"),
-	Failed:    mysql.NewEnumValue("FAILED"),
+	Pending:      mysql.NewEnumValue("PENDING"),
+	Running:      mysql.NewEnumValue("RUNNING"),
+	Completed:    mysql.NewEnumValue("COMPLETED"),
+	Failed:       mysql.NewEnumValue("FAILED"),
+	Compensating: mysql.NewEnumValue("COMPENSATING"),
+	Compensated:  mysql.NewEnumValue("COMPENSATED"),
+	Cancelled:    mysql.NewEnumValue("CANCELLED"),
+	Scheduled:    mysql.NewEnumValue("SCHEDULED"),
 }
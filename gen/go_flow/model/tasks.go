@@ -21,6 +21,10 @@ type Tasks struct {
 	OutputPayload      *string
 	ErrorMessage       *string
 	ScheduledAt        *time.Time
+	ClaimedBy          *string
+	ClaimedAt          *time.Time
+	LeaseExpiresAt     *time.Time
+	WaitUntil          *time.Time
 	CreatedAt          *time.Time
 	UpdatedAt          *time.Time
 }
@@ -17,6 +17,8 @@ const (
 	TasksStatus_Completed  TasksStatus = "COMPLETED"
 	TasksStatus_Failed     TasksStatus = "FAILED"
 	TasksStatus_Retrying   TasksStatus = "RETRYING"
+	TasksStatus_Waiting    TasksStatus = "WAITING"
+	TasksStatus_Cancelled  TasksStatus = "CANCELLED"
 )
 
 var TasksStatusAllValues = []TasksStatus{
@@ -25,6 +27,8 @@ var TasksStatusAllValues = []TasksStatus{
 	TasksStatus_Completed,
 	TasksStatus_Failed,
 	TasksStatus_Retrying,
+	TasksStatus_Waiting,
+	TasksStatus_Cancelled,
 }
 
 func (e *TasksStatus) Scan(value interface{}) error {
@@ -49,6 +53,10 @@ func (e *TasksStatus) Scan(value interface{}) error {
 		*e = TasksStatus_Failed
 	case "RETRYING":
 		*e = TasksStatus_Retrying
+	case "WAITING":
+		*e = TasksStatus_Waiting
+	case "CANCELLED":
+		*e = TasksStatus_Cancelled
 	default:
 		return errors.New("jet: Invalid scan value '" + enumValue + "' for TasksStatus enum")
 	}
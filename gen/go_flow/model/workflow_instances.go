@@ -12,11 +12,18 @@ import (
 )
 
 type WorkflowInstances struct {
-	ID            string `sql:"primary_key"`
-	WorkflowName  string
-	Status        *WorkflowInstancesStatus
-	CurrentInput  *string
-	CurrentOutput *string
-	CreatedAt     *time.Time
-	UpdatedAt     *time.Time
+	ID              string `sql:"primary_key"`
+	WorkflowName    string
+	WorkflowVersion int32
+	Status          *WorkflowInstancesStatus
+	CurrentInput    *string
+	CurrentOutput   *string
+	State           *string
+	DeadlineAt      *time.Time
+	ParentID        *string
+	ParentTaskID    *int64
+	IdempotencyKey  *string
+	RequestHash     *string
+	CreatedAt       *time.Time
+	UpdatedAt       *time.Time
 }
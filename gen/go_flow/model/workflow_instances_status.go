@@ -12,10 +12,14 @@ import "errors"
 type WorkflowInstancesStatus string
 
 const (
-	WorkflowInstancesStatus_Pending   WorkflowInstancesStatus = "PENDING"
-	WorkflowInstancesStatus_Running   WorkflowInstancesStatus = "RUNNING"
-	WorkflowInstancesStatus_Completed WorkflowInstancesStatus = "COMPLETED"
-	WorkflowInstancesStatus_Failed    WorkflowInstancesStatus = "FAILED"
+	WorkflowInstancesStatus_Pending      WorkflowInstancesStatus = "PENDING"
+	WorkflowInstancesStatus_Running      WorkflowInstancesStatus = "RUNNING"
+	WorkflowInstancesStatus_Completed    WorkflowInstancesStatus = "COMPLETED"
+	WorkflowInstancesStatus_Failed       WorkflowInstancesStatus = "FAILED"
+	WorkflowInstancesStatus_Compensating WorkflowInstancesStatus = "COMPENSATING"
+	WorkflowInstancesStatus_Compensated  WorkflowInstancesStatus = "COMPENSATED"
+	WorkflowInstancesStatus_Cancelled    WorkflowInstancesStatus = "CANCELLED"
+	WorkflowInstancesStatus_Scheduled    WorkflowInstancesStatus = "SCHEDULED"
 )
 
 var WorkflowInstancesStatusAllValues = []WorkflowInstancesStatus{
@@ -23,6 +27,10 @@ var WorkflowInstancesStatusAllValues = []WorkflowInstancesStatus{
 	WorkflowInstancesStatus_Running,
 	WorkflowInstancesStatus_Completed,
 	WorkflowInstancesStatus_Failed,
+	WorkflowInstancesStatus_Compensating,
+	WorkflowInstancesStatus_Compensated,
+	WorkflowInstancesStatus_Cancelled,
+	WorkflowInstancesStatus_Scheduled,
 }
 
 func (e *WorkflowInstancesStatus) Scan(value interface{}) error {
@@ -45,6 +53,14 @@ func (e *WorkflowInstancesStatus) Scan(value interface{}) error {
 		*e = WorkflowInstancesStatus_Completed
 	case "FAILED":
 		*e = WorkflowInstancesStatus_Failed
+	case "COMPENSATING":
+		*e = WorkflowInstancesStatus_Compensating
+	case "COMPENSATED":
+		*e = WorkflowInstancesStatus_Compensated
+	case "CANCELLED":
+		*e = WorkflowInstancesStatus_Cancelled
+	case "SCHEDULED":
+		*e = WorkflowInstancesStatus_Scheduled
 	default:
 		return errors.New("jet: Invalid scan value '" + enumValue + "' for WorkflowInstancesStatus enum")
 	}
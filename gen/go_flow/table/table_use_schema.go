@@ -13,4 +13,6 @@ func UseSchema(schema string) {
 	ActivityLogs = ActivityLogs.FromSchema(schema)
 	Tasks = Tasks.FromSchema(schema)
 	WorkflowInstances = WorkflowInstances.FromSchema(schema)
+	WorkflowSchedules = WorkflowSchedules.FromSchema(schema)
+	WorkflowSignals = WorkflowSignals.FromSchema(schema)
 }
@@ -26,6 +26,10 @@ type tasksTable struct {
 	OutputPayload      mysql.ColumnString
 	ErrorMessage       mysql.ColumnString
 	ScheduledAt        mysql.ColumnTimestamp
+	ClaimedBy          mysql.ColumnString
+	ClaimedAt          mysql.ColumnTimestamp
+	LeaseExpiresAt     mysql.ColumnTimestamp
+	WaitUntil          mysql.ColumnTimestamp
 	CreatedAt          mysql.ColumnTimestamp
 	UpdatedAt          mysql.ColumnTimestamp
 
@@ -78,10 +82,14 @@ func newTasksTableImpl(schemaName, tableName, alias string) tasksTable {
 		OutputPayloadColumn      = mysql.StringColumn("output_payload")
 		ErrorMessageColumn       = mysql.StringColumn("error_message")
 		ScheduledAtColumn        = mysql.TimestampColumn("scheduled_at")
+		ClaimedByColumn          = mysql.StringColumn("claimed_by")
+		ClaimedAtColumn          = mysql.TimestampColumn("claimed_at")
+		LeaseExpiresAtColumn     = mysql.TimestampColumn("lease_expires_at")
+		WaitUntilColumn          = mysql.TimestampColumn("wait_until")
 		CreatedAtColumn          = mysql.TimestampColumn("created_at")
 		UpdatedAtColumn          = mysql.TimestampColumn("updated_at")
-		allColumns               = mysql.ColumnList{IDColumn, WorkflowInstanceIDColumn, TaskNameColumn, StatusColumn, RetryCountColumn, InputPayloadColumn, OutputPayloadColumn, ErrorMessageColumn, ScheduledAtColumn, CreatedAtColumn, UpdatedAtColumn}
-		mutableColumns           = mysql.ColumnList{WorkflowInstanceIDColumn, TaskNameColumn, StatusColumn, RetryCountColumn, InputPayloadColumn, OutputPayloadColumn, ErrorMessageColumn, ScheduledAtColumn, CreatedAtColumn, UpdatedAtColumn}
+		allColumns               = mysql.ColumnList{IDColumn, WorkflowInstanceIDColumn, TaskNameColumn, StatusColumn, RetryCountColumn, InputPayloadColumn, OutputPayloadColumn, ErrorMessageColumn, ScheduledAtColumn, ClaimedByColumn, ClaimedAtColumn, LeaseExpiresAtColumn, WaitUntilColumn, CreatedAtColumn, UpdatedAtColumn}
+		mutableColumns           = mysql.ColumnList{WorkflowInstanceIDColumn, TaskNameColumn, StatusColumn, RetryCountColumn, InputPayloadColumn, OutputPayloadColumn, ErrorMessageColumn, ScheduledAtColumn, ClaimedByColumn, ClaimedAtColumn, LeaseExpiresAtColumn, WaitUntilColumn, CreatedAtColumn, UpdatedAtColumn}
 		defaultColumns           = mysql.ColumnList{StatusColumn, RetryCountColumn, CreatedAtColumn, UpdatedAtColumn}
 	)
 
@@ -98,6 +106,10 @@ func newTasksTableImpl(schemaName, tableName, alias string) tasksTable {
 		OutputPayload:      OutputPayloadColumn,
 		ErrorMessage:       ErrorMessageColumn,
 		ScheduledAt:        ScheduledAtColumn,
+		ClaimedBy:          ClaimedByColumn,
+		ClaimedAt:          ClaimedAtColumn,
+		LeaseExpiresAt:     LeaseExpiresAtColumn,
+		WaitUntil:          WaitUntilColumn,
 		CreatedAt:          CreatedAtColumn,
 		UpdatedAt:          UpdatedAtColumn,
 
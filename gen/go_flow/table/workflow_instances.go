@@ -17,13 +17,20 @@ type workflowInstancesTable struct {
 	mysql.Table
 
 	// Columns
-	ID            mysql.ColumnString
-	WorkflowName  mysql.ColumnString
-	Status        mysql.ColumnString
-	CurrentInput  mysql.ColumnString
-	CurrentOutput mysql.ColumnString
-	CreatedAt     mysql.ColumnTimestamp
-	UpdatedAt     mysql.ColumnTimestamp
+	ID              mysql.ColumnString
+	WorkflowName    mysql.ColumnString
+	WorkflowVersion mysql.ColumnInteger
+	Status          mysql.ColumnString
+	CurrentInput    mysql.ColumnString
+	CurrentOutput   mysql.ColumnString
+	State           mysql.ColumnString
+	DeadlineAt      mysql.ColumnTimestamp
+	ParentID        mysql.ColumnString
+	ParentTaskID    mysql.ColumnInteger
+	IdempotencyKey  mysql.ColumnString
+	RequestHash     mysql.ColumnString
+	CreatedAt       mysql.ColumnTimestamp
+	UpdatedAt       mysql.ColumnTimestamp
 
 	AllColumns     mysql.ColumnList
 	MutableColumns mysql.ColumnList
@@ -65,29 +72,43 @@ func newWorkflowInstancesTable(schemaName, tableName, alias string) *WorkflowIns
 
 func newWorkflowInstancesTableImpl(schemaName, tableName, alias string) workflowInstancesTable {
 	var (
-		IDColumn            = mysql.StringColumn("id")
-		WorkflowNameColumn  = mysql.StringColumn("workflow_name")
-		StatusColumn        = mysql.StringColumn("status")
-		CurrentInputColumn  = mysql.StringColumn("current_input")
-		CurrentOutputColumn = mysql.StringColumn("current_output")
-		CreatedAtColumn     = mysql.TimestampColumn("created_at")
-		UpdatedAtColumn     = mysql.TimestampColumn("updated_at")
-		allColumns          = mysql.ColumnList{IDColumn, WorkflowNameColumn, StatusColumn, CurrentInputColumn, CurrentOutputColumn, CreatedAtColumn, UpdatedAtColumn}
-		mutableColumns      = mysql.ColumnList{WorkflowNameColumn, StatusColumn, CurrentInputColumn, CurrentOutputColumn, CreatedAtColumn, UpdatedAtColumn}
-		defaultColumns      = mysql.ColumnList{StatusColumn, CreatedAtColumn, UpdatedAtColumn}
+		IDColumn              = mysql.StringColumn("id")
+		WorkflowNameColumn    = mysql.StringColumn("workflow_name")
+		WorkflowVersionColumn = mysql.IntegerColumn("workflow_version")
+		StatusColumn          = mysql.StringColumn("status")
+		CurrentInputColumn    = mysql.StringColumn("current_input")
+		CurrentOutputColumn   = mysql.StringColumn("current_output")
+		StateColumn           = mysql.StringColumn("state")
+		DeadlineAtColumn      = mysql.TimestampColumn("deadline_at")
+		ParentIDColumn        = mysql.StringColumn("parent_id")
+		ParentTaskIDColumn    = mysql.IntegerColumn("parent_task_id")
+		IdempotencyKeyColumn  = mysql.StringColumn("idempotency_key")
+		RequestHashColumn     = mysql.StringColumn("request_hash")
+		CreatedAtColumn       = mysql.TimestampColumn("created_at")
+		UpdatedAtColumn       = mysql.TimestampColumn("updated_at")
+		allColumns            = mysql.ColumnList{IDColumn, WorkflowNameColumn, WorkflowVersionColumn, StatusColumn, CurrentInputColumn, CurrentOutputColumn, StateColumn, DeadlineAtColumn, ParentIDColumn, ParentTaskIDColumn, IdempotencyKeyColumn, RequestHashColumn, CreatedAtColumn, UpdatedAtColumn}
+		mutableColumns        = mysql.ColumnList{WorkflowNameColumn, WorkflowVersionColumn, StatusColumn, CurrentInputColumn, CurrentOutputColumn, StateColumn, DeadlineAtColumn, ParentIDColumn, ParentTaskIDColumn, IdempotencyKeyColumn, RequestHashColumn, CreatedAtColumn, UpdatedAtColumn}
+		defaultColumns        = mysql.ColumnList{WorkflowVersionColumn, StatusColumn, CreatedAtColumn, UpdatedAtColumn}
 	)
 
 	return workflowInstancesTable{
 		Table: mysql.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:            IDColumn,
-		WorkflowName:  WorkflowNameColumn,
-		Status:        StatusColumn,
-		CurrentInput:  CurrentInputColumn,
-		CurrentOutput: CurrentOutputColumn,
-		CreatedAt:     CreatedAtColumn,
-		UpdatedAt:     UpdatedAtColumn,
+		ID:              IDColumn,
+		WorkflowName:    WorkflowNameColumn,
+		WorkflowVersion: WorkflowVersionColumn,
+		Status:          StatusColumn,
+		CurrentInput:    CurrentInputColumn,
+		CurrentOutput:   CurrentOutputColumn,
+		State:           StateColumn,
+		DeadlineAt:      DeadlineAtColumn,
+		ParentID:        ParentIDColumn,
+		ParentTaskID:    ParentTaskIDColumn,
+		IdempotencyKey:  IdempotencyKeyColumn,
+		RequestHash:     RequestHashColumn,
+		CreatedAt:       CreatedAtColumn,
+		UpdatedAt:       UpdatedAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-jet/jet/v2/mysql"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/table"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+)
+
+type scheduleRepo struct {
+	db *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) port.ScheduleRepository {
+	return &scheduleRepo{db: db}
+}
+
+func (r *scheduleRepo) CreateSchedule(ctx context.Context, schedule *model.WorkflowSchedules) error {
+	stmt := table.WorkflowSchedules.INSERT(
+		table.WorkflowSchedules.ID,
+		table.WorkflowSchedules.Name,
+		table.WorkflowSchedules.WorkflowName,
+		table.WorkflowSchedules.CronExpression,
+		table.WorkflowSchedules.TimeZone,
+		table.WorkflowSchedules.InputPayload,
+		table.WorkflowSchedules.OverlapPolicy,
+		table.WorkflowSchedules.Paused,
+		table.WorkflowSchedules.NextRunAt,
+	).MODEL(schedule)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+func (r *scheduleRepo) GetScheduleByID(ctx context.Context, id string) (*model.WorkflowSchedules, error) {
+	var dest []model.WorkflowSchedules
+	stmt := table.WorkflowSchedules.SELECT(
+		table.WorkflowSchedules.AllColumns,
+	).WHERE(
+		table.WorkflowSchedules.ID.EQ(mysql.String(id)),
+	)
+
+	if err := stmt.QueryContext(ctx, r.conn(ctx), &dest); err != nil {
+		return nil, err
+	}
+	if len(dest) == 0 {
+		return nil, port.ErrScheduleNotFound
+	}
+
+	return &dest[0], nil
+}
+
+func (r *scheduleRepo) ListSchedules(ctx context.Context, limit int, offset int) ([]model.WorkflowSchedules, error) {
+	var dest []model.WorkflowSchedules
+
+	stmt := table.WorkflowSchedules.SELECT(
+		table.WorkflowSchedules.AllColumns,
+	).FROM(
+		table.WorkflowSchedules,
+	).ORDER_BY(
+		table.WorkflowSchedules.CreatedAt.DESC(),
+	).LIMIT(int64(limit)).OFFSET(int64(offset))
+
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
+
+	return dest, err
+}
+
+// UpdateSchedule saves the user-editable fields and the recomputed next run
+func (r *scheduleRepo) UpdateSchedule(ctx context.Context, schedule *model.WorkflowSchedules) error {
+	stmt := table.WorkflowSchedules.UPDATE(
+		table.WorkflowSchedules.Name,
+		table.WorkflowSchedules.WorkflowName,
+		table.WorkflowSchedules.CronExpression,
+		table.WorkflowSchedules.TimeZone,
+		table.WorkflowSchedules.InputPayload,
+		table.WorkflowSchedules.OverlapPolicy,
+		table.WorkflowSchedules.Paused,
+		table.WorkflowSchedules.Buffered,
+		table.WorkflowSchedules.NextRunAt,
+	).MODEL(schedule).WHERE(
+		table.WorkflowSchedules.ID.EQ(mysql.String(schedule.ID)),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+func (r *scheduleRepo) DeleteSchedule(ctx context.Context, id string) (bool, error) {
+	stmt := table.WorkflowSchedules.DELETE().WHERE(
+		table.WorkflowSchedules.ID.EQ(mysql.String(id)),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// dueScheduleCondition matches active schedules whose next run has come, or
+// that hold a buffered run waiting for the previous instance to finish
+func dueScheduleCondition(now time.Time) mysql.BoolExpression {
+	return table.WorkflowSchedules.Paused.IS_FALSE().
+		AND(table.WorkflowSchedules.NextRunAt.LT_EQ(mysql.TimestampT(now)).
+			OR(table.WorkflowSchedules.Buffered.IS_TRUE()))
+}
+
+// GetDueScheduleIDs lists schedules to fire without locking them
+func (r *scheduleRepo) GetDueScheduleIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	var dest []model.WorkflowSchedules
+
+	stmt := table.WorkflowSchedules.SELECT(
+		table.WorkflowSchedules.ID,
+	).FROM(
+		table.WorkflowSchedules,
+	).WHERE(
+		dueScheduleCondition(now),
+	).ORDER_BY(
+		table.WorkflowSchedules.NextRunAt.ASC(),
+	).LIMIT(int64(limit))
+
+	if err := stmt.QueryContext(ctx, r.conn(ctx), &dest); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(dest))
+	for _, schedule := range dest {
+		ids = append(ids, schedule.ID)
+	}
+
+	return ids, nil
+}
+
+// LockDueSchedule re-checks a schedule under a row lock so only one replica
+// fires each run
+func (r *scheduleRepo) LockDueSchedule(ctx context.Context, id string, now time.Time) (*model.WorkflowSchedules, error) {
+	var dest []model.WorkflowSchedules
+	stmt := table.WorkflowSchedules.SELECT(
+		table.WorkflowSchedules.AllColumns,
+	).WHERE(
+		table.WorkflowSchedules.ID.EQ(mysql.String(id)).
+			AND(dueScheduleCondition(now)),
+	).FOR(mysql.UPDATE().SKIP_LOCKED())
+
+	if err := stmt.QueryContext(ctx, r.conn(ctx), &dest); err != nil {
+		return nil, err
+	}
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	return &dest[0], nil
+}
+
+// UpdateScheduleRun records a fired (or skipped) run and the next one
+func (r *scheduleRepo) UpdateScheduleRun(ctx context.Context, schedule *model.WorkflowSchedules) error {
+	stmt := table.WorkflowSchedules.UPDATE(
+		table.WorkflowSchedules.NextRunAt,
+		table.WorkflowSchedules.Buffered,
+		table.WorkflowSchedules.LastRunAt,
+		table.WorkflowSchedules.LastWorkflowID,
+		table.WorkflowSchedules.LastError,
+	).MODEL(schedule).WHERE(
+		table.WorkflowSchedules.ID.EQ(mysql.String(schedule.ID)),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+// RecordScheduleFailure saves the error of a failed run with the next run
+// (or the schedule paused)
+func (r *scheduleRepo) RecordScheduleFailure(ctx context.Context, schedule *model.WorkflowSchedules) error {
+	stmt := table.WorkflowSchedules.UPDATE(
+		table.WorkflowSchedules.NextRunAt,
+		table.WorkflowSchedules.Buffered,
+		table.WorkflowSchedules.Paused,
+		table.WorkflowSchedules.LastError,
+	).MODEL(schedule).WHERE(
+		table.WorkflowSchedules.ID.EQ(mysql.String(schedule.ID)),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-jet/jet/v2/qrm"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// txKey carries the current *sql.Tx in a context
+type txKey struct{}
+
+// conn returns the transaction bound to ctx by WithTx, or the plain connection pool
+func (r *workflowRepo) conn(ctx context.Context) qrm.DB {
+	return txOrDB(ctx, r.db)
+}
+
+// conn joins the transaction of WorkflowRepository.WithTx like workflowRepo
+func (r *scheduleRepo) conn(ctx context.Context) qrm.DB {
+	return txOrDB(ctx, r.db)
+}
+
+func txOrDB(ctx context.Context, db *sql.DB) qrm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// WithTx runs fn in a transaction. Repository calls made with the context
+// passed to fn join that transaction; it commits if fn returns nil and rolls
+// back otherwise. Nested calls reuse the outer transaction
+func (r *workflowRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// isDuplicateKey reports a MySQL unique constraint violation (error 1062)
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
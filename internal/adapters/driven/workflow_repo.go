@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/go-jet/jet/v2/mysql"
 	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
@@ -23,11 +24,22 @@ func (r *workflowRepo) CreateWorkflow(ctx context.Context, wf *model.WorkflowIns
 		INSERT(
 			table.WorkflowInstances.ID,
 			table.WorkflowInstances.WorkflowName,
+			table.WorkflowInstances.WorkflowVersion,
 			table.WorkflowInstances.Status,
 			table.WorkflowInstances.CurrentInput,
+			table.WorkflowInstances.State,
+			table.WorkflowInstances.DeadlineAt,
+			table.WorkflowInstances.ParentID,
+			table.WorkflowInstances.ParentTaskID,
+			table.WorkflowInstances.IdempotencyKey,
+			table.WorkflowInstances.RequestHash,
 		).MODEL(wf) // map struct เข้า db อัตโนมัตฺิ
 
-	_, err := stmt.ExecContext(ctx, r.db)
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if isDuplicateKey(err) {
+		// uq_workflow_instances_idempotency_key: a concurrent request with the same key won
+		return port.ErrIdempotencyKeyExists
+	}
 
 	return err
 }
@@ -38,9 +50,14 @@ func (r *workflowRepo) CreateTask(ctx context.Context, task *model.Tasks) error
 			table.Tasks.TaskName,
 			table.Tasks.Status,
 			table.Tasks.InputPayload,
+			table.Tasks.ScheduledAt,
 		).MODEL(task) // map struct เข้า db อัตโนมัตฺิ
 
-	_, err := stmt.ExecContext(ctx, r.db)
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if isDuplicateKey(err) {
+		// uq_tasks_workflow_task: this step already exists for the workflow
+		return port.ErrTaskAlreadyExists
+	}
 
 	return err
 }
@@ -56,7 +73,7 @@ func (r *workflowRepo) GetWorkflowPending(ctx context.Context, limit int) ([]mod
 		table.WorkflowInstances.Status.EQ(mysql.String("PENDING")),
 	).LIMIT(int64(limit))
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
 
 	return dest, err
 }
@@ -72,7 +89,7 @@ func (r *workflowRepo) ListWorkflows(ctx context.Context, limit int, offset int)
 		table.WorkflowInstances.CreatedAt.DESC(),
 	).LIMIT(int64(limit)).OFFSET(int64(offset))
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
 
 	return dest, err
 }
@@ -88,7 +105,7 @@ func (r *workflowRepo) CountWorkflows(ctx context.Context) (int64, error) {
 		table.WorkflowInstances,
 	)
 
-	err := stmt.QueryContext(ctx, r.db, &count)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &count)
 	if err != nil {
 		return 0, err
 	}
@@ -105,37 +122,298 @@ func (r *workflowRepo) UpdateWorkflowStatus(ctx context.Context, id string, stat
 		table.WorkflowInstances.ID.EQ(mysql.String(id)),
 	)
 
-	_, err := stmt.ExecContext(ctx, r.db)
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+func (r *workflowRepo) UpdateWorkflowOutput(ctx context.Context, id string, output string) error {
+	stmt := table.WorkflowInstances.UPDATE(
+		table.WorkflowInstances.CurrentOutput,
+	).SET(
+		output,
+	).WHERE(
+		table.WorkflowInstances.ID.EQ(mysql.String(id)),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+// UpdateWorkflowState saves the accumulated state (input and step outputs)
+func (r *workflowRepo) UpdateWorkflowState(ctx context.Context, id string, state string) error {
+	stmt := table.WorkflowInstances.UPDATE(
+		table.WorkflowInstances.State,
+	).SET(
+		state,
+	).WHERE(
+		table.WorkflowInstances.ID.EQ(mysql.String(id)),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
 
 	return err
 }
 
+// TransitionWorkflowStatus sets status only if the workflow is currently in one
+// of from, so concurrent workers apply a transition once
+func (r *workflowRepo) TransitionWorkflowStatus(ctx context.Context, id string, status string, from ...string) (bool, error) {
+	fromExprs := make([]mysql.Expression, 0, len(from))
+	for _, f := range from {
+		fromExprs = append(fromExprs, mysql.String(f))
+	}
+
+	stmt := table.WorkflowInstances.UPDATE(
+		table.WorkflowInstances.Status,
+	).SET(
+		status,
+	).WHERE(
+		table.WorkflowInstances.ID.EQ(mysql.String(id)).
+			AND(table.WorkflowInstances.Status.IN(fromExprs...)),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// GetTimedOutWorkflows returns unfinished workflows whose deadline has passed
+func (r *workflowRepo) GetTimedOutWorkflows(ctx context.Context, limit int) ([]model.WorkflowInstances, error) {
+	var dest []model.WorkflowInstances
+
+	stmt := table.WorkflowInstances.SELECT(
+		table.WorkflowInstances.AllColumns,
+	).FROM(
+		table.WorkflowInstances,
+	).WHERE(
+		table.WorkflowInstances.Status.IN(mysql.String("SCHEDULED"), mysql.String("PENDING"), mysql.String("RUNNING")).
+			AND(table.WorkflowInstances.DeadlineAt.LT(mysql.TimestampT(time.Now()))),
+	).LIMIT(int64(limit))
+
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
+
+	return dest, err
+}
+
 func (r *workflowRepo) GetWorkflowByID(ctx context.Context, id string) (*model.WorkflowInstances, error) {
 	var dest model.WorkflowInstances
 	stmt := table.WorkflowInstances.SELECT(
 		table.WorkflowInstances.AllColumns,
 	).WHERE(table.WorkflowInstances.ID.IN(mysql.String(id)))
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
 
 	return &dest, err
 }
 
-func (r *workflowRepo) GetTaskPending(ctx context.Context, limit int) ([]model.Tasks, error) {
+// GetWorkflowByIdempotencyKey returns the instance started with an idempotency key
+func (r *workflowRepo) GetWorkflowByIdempotencyKey(ctx context.Context, key string) (*model.WorkflowInstances, error) {
+	var dest []model.WorkflowInstances
+	stmt := table.WorkflowInstances.SELECT(
+		table.WorkflowInstances.AllColumns,
+	).WHERE(
+		table.WorkflowInstances.IdempotencyKey.EQ(mysql.String(key)),
+	)
+
+	if err := stmt.QueryContext(ctx, r.conn(ctx), &dest); err != nil {
+		return nil, err
+	}
+	if len(dest) == 0 {
+		return nil, nil
+	}
+
+	return &dest[0], nil
+}
+
+// LockWorkflow reads a workflow and locks its row until the surrounding
+// transaction ends, serializing step transitions of the same instance
+func (r *workflowRepo) LockWorkflow(ctx context.Context, id string) (*model.WorkflowInstances, error) {
+	var dest model.WorkflowInstances
+	stmt := table.WorkflowInstances.SELECT(
+		table.WorkflowInstances.AllColumns,
+	).WHERE(
+		table.WorkflowInstances.ID.EQ(mysql.String(id)),
+	).FOR(mysql.UPDATE())
+
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
+
+	return &dest, err
+}
+
+// GetChildWorkflows returns the instances started by steps of a workflow
+func (r *workflowRepo) GetChildWorkflows(ctx context.Context, parentID string) ([]model.WorkflowInstances, error) {
+	var dest []model.WorkflowInstances
+	stmt := table.WorkflowInstances.SELECT(
+		table.WorkflowInstances.AllColumns,
+	).WHERE(
+		table.WorkflowInstances.ParentID.EQ(mysql.String(parentID)),
+	).ORDER_BY(
+		table.WorkflowInstances.CreatedAt.ASC(),
+	)
+
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
+
+	return dest, err
+}
+
+// readyTaskCondition matches PENDING tasks whose scheduled time (if any) has passed
+func readyTaskCondition() mysql.BoolExpression {
+	return table.Tasks.Status.EQ(mysql.String("PENDING")).
+		AND(table.Tasks.ScheduledAt.IS_NULL().
+			OR(table.Tasks.ScheduledAt.LT_EQ(mysql.TimestampT(time.Now()))))
+}
+
+// ClaimTasks locks up to limit ready PENDING tasks, skipping rows already locked by
+// other workers, and marks them IN_PROGRESS for workerID in one transaction
+func (r *workflowRepo) ClaimTasks(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Tasks, error) {
+	var dest []model.Tasks
+
+	err := r.WithTx(ctx, func(ctx context.Context) error {
+		stmt := table.Tasks.SELECT(
+			table.Tasks.AllColumns,
+		).FROM(
+			table.Tasks,
+		).WHERE(
+			readyTaskCondition(),
+		).ORDER_BY(
+			table.Tasks.ID.ASC(),
+		).LIMIT(int64(limit)).FOR(mysql.UPDATE().SKIP_LOCKED())
+
+		if err := stmt.QueryContext(ctx, r.conn(ctx), &dest); err != nil {
+			return err
+		}
+
+		if len(dest) == 0 {
+			return nil
+		}
+
+		ids := make([]mysql.Expression, 0, len(dest))
+		for _, task := range dest {
+			ids = append(ids, mysql.Int(task.ID))
+		}
+
+		claimedAt := time.Now()
+		leaseExpiresAt := claimedAt.Add(lease)
+		update := table.Tasks.UPDATE(
+			table.Tasks.Status,
+			table.Tasks.ClaimedBy,
+			table.Tasks.ClaimedAt,
+			table.Tasks.LeaseExpiresAt,
+		).SET(
+			"IN_PROGRESS",
+			workerID,
+			claimedAt,
+			leaseExpiresAt,
+		).WHERE(
+			table.Tasks.ID.IN(ids...),
+		)
+
+		if _, err := update.ExecContext(ctx, r.conn(ctx)); err != nil {
+			return err
+		}
+
+		status := model.TasksStatus_InProgress
+		for i := range dest {
+			dest[i].Status = &status
+			dest[i].ClaimedBy = &workerID
+			dest[i].ClaimedAt = &claimedAt
+			dest[i].LeaseExpiresAt = &leaseExpiresAt
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// RenewTaskLease extends the lease of a task still claimed by workerID.
+// It returns false when the claim was lost (e.g. reaped after expiry)
+func (r *workflowRepo) RenewTaskLease(ctx context.Context, id int, workerID string, lease time.Duration) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		time.Now().Add(lease),
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))).
+			AND(table.Tasks.ClaimedBy.EQ(mysql.String(workerID))).
+			AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING"))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// GetExpiredTasks returns claimed tasks whose lease has run out
+func (r *workflowRepo) GetExpiredTasks(ctx context.Context, limit int) ([]model.Tasks, error) {
 	var dest []model.Tasks
 	stmt := table.Tasks.SELECT(
 		table.Tasks.AllColumns,
 	).FROM(
 		table.Tasks,
 	).WHERE(
-		table.Tasks.Status.EQ(mysql.String("PENDING")),
+		table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING")).
+			AND(table.Tasks.LeaseExpiresAt.LT(mysql.TimestampT(time.Now()))),
+	).ORDER_BY(
+		table.Tasks.LeaseExpiresAt.ASC(),
 	).LIMIT(int64(limit))
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
 
 	return dest, err
 }
 
+// ReleaseExpiredTask clears the claim of an expired task and moves it to status.
+// It only succeeds if the task is still held by the same (expired) claim, so
+// concurrent reapers release each task exactly once
+func (r *workflowRepo) ReleaseExpiredTask(ctx context.Context, task model.Tasks, status string, retryCount int) (bool, error) {
+	if task.ClaimedBy == nil {
+		return false, nil
+	}
+
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.RetryCount,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		status,
+		retryCount,
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(task.ID)).
+			AND(table.Tasks.ClaimedBy.EQ(mysql.String(*task.ClaimedBy))).
+			AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING"))).
+			AND(table.Tasks.LeaseExpiresAt.LT(mysql.TimestampT(time.Now()))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
 func (r *workflowRepo) UpdateTaskStatus(ctx context.Context, id int, status string) error {
 	stmt := table.Tasks.UPDATE(
 		table.Tasks.Status,
@@ -145,7 +423,7 @@ func (r *workflowRepo) UpdateTaskStatus(ctx context.Context, id int, status stri
 		table.Tasks.ID.EQ(mysql.Int(int64(id))),
 	)
 
-	_, err := stmt.ExecContext(ctx, r.db)
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
 
 	return err
 }
@@ -163,7 +441,7 @@ func (r *workflowRepo) GetTasksByWorkflowID(ctx context.Context, wfID string) ([
 		table.Tasks.ID.ASC(),
 	)
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
 
 	return dest, err
 }
@@ -177,7 +455,7 @@ func (r *workflowRepo) CreateActivityLog(ctx context.Context, log *model.Activit
 			table.ActivityLogs.Details,
 		).MODEL(log)
 
-	_, err := stmt.ExecContext(ctx, r.db)
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
 
 	return err
 }
@@ -195,36 +473,355 @@ func (r *workflowRepo) GetActivityLogsByWorkflowID(ctx context.Context, wfID str
 		table.ActivityLogs.CreatedAt.ASC(),
 	)
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	err := stmt.QueryContext(ctx, r.conn(ctx), &dest)
 
 	return dest, err
 }
 
-func (r *workflowRepo) UpdateTaskRetryCount(ctx context.Context, id int, retryCount int) error {
+// CompleteTask marks a task COMPLETED with its output and clears its claim.
+// It returns false if workerID no longer holds the claim
+func (r *workflowRepo) CompleteTask(ctx context.Context, id int, workerID string, output *string) (bool, error) {
+	var outputValue any = mysql.NULL
+	if output != nil {
+		outputValue = *output
+	}
+
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.OutputPayload,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		"COMPLETED",
+		outputValue,
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))).
+			AND(table.Tasks.ClaimedBy.EQ(mysql.String(workerID))).
+			AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING"))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (r *workflowRepo) UpdateTaskErrorMessage(ctx context.Context, id int, message string) error {
 	stmt := table.Tasks.UPDATE(
+		table.Tasks.ErrorMessage,
+	).SET(
+		message,
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+// UpdatePendingTasksStatus sets the status of every task of a workflow that
+// is not running: waiting to be claimed or waiting on a child workflow
+func (r *workflowRepo) UpdatePendingTasksStatus(ctx context.Context, wfID string, status string) error {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+	).SET(
+		status,
+	).WHERE(
+		table.Tasks.WorkflowInstanceID.EQ(mysql.String(wfID)).
+			AND(table.Tasks.Status.IN(mysql.String("PENDING"), mysql.String("WAITING"))),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
+}
+
+// ParkTask moves a task this worker holds to WAITING and drops the claim, so
+// it occupies no worker until WakeTask puts it back in the queue
+func (r *workflowRepo) ParkTask(ctx context.Context, id int, workerID string) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		"WAITING",
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))).
+			AND(table.Tasks.ClaimedBy.EQ(mysql.String(workerID))).
+			AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING"))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// WakeTask moves a WAITING task back to PENDING so a worker picks it up
+func (r *workflowRepo) WakeTask(ctx context.Context, id int) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+	).SET(
+		"PENDING",
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))).
+			AND(table.Tasks.Status.EQ(mysql.String("WAITING"))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// ReleaseTaskClaims returns every running task claimed by workerID to PENDING
+// without counting an attempt. Used when a worker shuts down
+func (r *workflowRepo) ReleaseTaskClaims(ctx context.Context, workerID string) (int64, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		"PENDING",
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
+	).WHERE(
+		table.Tasks.ClaimedBy.EQ(mysql.String(workerID)).
+			AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING"))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// claimedTaskCondition matches a task that workerID still holds
+func claimedTaskCondition(id int, workerID string) mysql.BoolExpression {
+	return table.Tasks.ID.EQ(mysql.Int(int64(id))).
+		AND(table.Tasks.ClaimedBy.EQ(mysql.String(workerID))).
+		AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING")))
+}
+
+// FailTask marks a task workerID holds as FAILED and clears its claim. It
+// returns false if the claim was lost
+func (r *workflowRepo) FailTask(ctx context.Context, id int, workerID string) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		"FAILED",
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
+	).WHERE(
+		claimedTaskCondition(id, workerID),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// MarkTaskRetrying flags a retried task workerID holds as RETRYING. It
+// returns false if the claim was lost
+func (r *workflowRepo) MarkTaskRetrying(ctx context.Context, id int, workerID string) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+	).SET(
+		"RETRYING",
+	).WHERE(
+		claimedTaskCondition(id, workerID),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// ScheduleTaskRetry returns a task workerID holds to PENDING with its claim
+// cleared; it will not be picked up again before scheduledAt. It returns
+// false if the claim was lost
+func (r *workflowRepo) ScheduleTaskRetry(ctx context.Context, id int, workerID string, retryCount int, scheduledAt time.Time) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
 		table.Tasks.RetryCount,
+		table.Tasks.ScheduledAt,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
 	).SET(
+		"PENDING",
 		retryCount,
+		scheduledAt,
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
 	).WHERE(
-		table.Tasks.ID.EQ(mysql.Int(int64(id))),
+		claimedTaskCondition(id, workerID),
 	)
 
-	_, err := stmt.ExecContext(ctx, r.db)
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// DeferTask puts a task this worker holds back in the queue until the given
+// time, dropping the claim. until is kept in wait_until as the step's
+// deadline; scheduled_at is also moved by retries and delayed starts
+func (r *workflowRepo) DeferTask(ctx context.Context, id int, workerID string, until time.Time) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.ScheduledAt,
+		table.Tasks.WaitUntil,
+		table.Tasks.ClaimedBy,
+		table.Tasks.ClaimedAt,
+		table.Tasks.LeaseExpiresAt,
+	).SET(
+		"PENDING",
+		until,
+		until,
+		mysql.NULL,
+		mysql.NULL,
+		mysql.NULL,
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))).
+			AND(table.Tasks.ClaimedBy.EQ(mysql.String(workerID))).
+			AND(table.Tasks.Status.IN(mysql.String("IN_PROGRESS"), mysql.String("RETRYING"))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// ResumeTask makes a WAITING task, or one deferred until its signal timeout,
+// claimable right away. A PENDING task waiting for a retry backoff or a
+// delayed start keeps its scheduled_at
+func (r *workflowRepo) ResumeTask(ctx context.Context, id int) (bool, error) {
+	stmt := table.Tasks.UPDATE(
+		table.Tasks.Status,
+		table.Tasks.ScheduledAt,
+	).SET(
+		"PENDING",
+		mysql.NULL,
+	).WHERE(
+		table.Tasks.ID.EQ(mysql.Int(int64(id))).
+			AND(table.Tasks.Status.EQ(mysql.String("WAITING")).
+				OR(table.Tasks.Status.EQ(mysql.String("PENDING")).
+					AND(table.Tasks.WaitUntil.IS_NOT_NULL()).
+					AND(table.Tasks.ScheduledAt.EQ(table.Tasks.WaitUntil)))),
+	)
+
+	res, err := stmt.ExecContext(ctx, r.conn(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (r *workflowRepo) CreateSignal(ctx context.Context, signal *model.WorkflowSignals) error {
+	stmt := table.WorkflowSignals.INSERT(
+		table.WorkflowSignals.WorkflowInstanceID,
+		table.WorkflowSignals.SignalName,
+		table.WorkflowSignals.Payload,
+	).MODEL(signal)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
 
 	return err
 }
 
-func (r *workflowRepo) GetTasksForRetry(ctx context.Context, limit int) ([]model.Tasks, error) {
-	var dest []model.Tasks
-	stmt := table.Tasks.SELECT(
-		table.Tasks.AllColumns,
-	).FROM(
-		table.Tasks,
+// GetSignalForTask returns the signal a waiting task should receive: the one
+// it already consumed (if its completion was lost), else the oldest buffered
+// one. It returns nil when no signal is available
+func (r *workflowRepo) GetSignalForTask(ctx context.Context, wfID string, name string, taskID int64) (*model.WorkflowSignals, error) {
+	var dest []model.WorkflowSignals
+	stmt := table.WorkflowSignals.SELECT(
+		table.WorkflowSignals.AllColumns,
 	).WHERE(
-		table.Tasks.Status.EQ(mysql.String("FAILED")),
-	).LIMIT(int64(limit))
+		table.WorkflowSignals.WorkflowInstanceID.EQ(mysql.String(wfID)).
+			AND(table.WorkflowSignals.SignalName.EQ(mysql.String(name))).
+			AND(table.WorkflowSignals.ConsumedByTaskID.EQ(mysql.Int(taskID)).
+				OR(table.WorkflowSignals.ConsumedByTaskID.IS_NULL())),
+	).ORDER_BY(
+		table.WorkflowSignals.ConsumedByTaskID.IS_NULL().ASC(),
+		table.WorkflowSignals.ID.ASC(),
+	).LIMIT(1).FOR(mysql.UPDATE())
 
-	err := stmt.QueryContext(ctx, r.db, &dest)
+	if err := stmt.QueryContext(ctx, r.conn(ctx), &dest); err != nil {
+		return nil, err
+	}
+	if len(dest) == 0 {
+		return nil, nil
+	}
 
-	return dest, err
+	return &dest[0], nil
+}
+
+// ConsumeSignal marks a buffered signal as delivered to a task
+func (r *workflowRepo) ConsumeSignal(ctx context.Context, id int64, taskID int64) error {
+	stmt := table.WorkflowSignals.UPDATE(
+		table.WorkflowSignals.ConsumedByTaskID,
+		table.WorkflowSignals.ConsumedAt,
+	).SET(
+		taskID,
+		time.Now(),
+	).WHERE(
+		table.WorkflowSignals.ID.EQ(mysql.Int(id)),
+	)
+
+	_, err := stmt.ExecContext(ctx, r.conn(ctx))
+
+	return err
 }
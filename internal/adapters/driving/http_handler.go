@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
 	"github.com/parinyadagon/go-workflow/internal/core/port"
 )
 
@@ -31,6 +34,16 @@ func (h *workflowHandler) StartWorkflow(c echo.Context) error {
 		})
 	}
 
+	// Idempotency-Key header ใช้แทน idempotency_key ใน body ได้
+	if key := c.Request().Header.Get("Idempotency-Key"); key != "" {
+		if req.IdempotencyKey != "" && req.IdempotencyKey != key {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Idempotency-Key header and idempotency_key differ",
+			})
+		}
+		req.IdempotencyKey = key
+	}
+
 	// Validate request
 	if err := h.validator.Struct(req); err != nil {
 		validationErrors := make(map[string]string)
@@ -56,6 +69,19 @@ func (h *workflowHandler) StartWorkflow(c echo.Context) error {
 
 	result, err := h.svc.StartNewWorkflow(c.Request().Context(), req)
 	if err != nil {
+		var inputErr *port.InputValidationError
+		if errors.As(err, &inputErr) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error":  "Validation failed",
+				"fields": inputErr.Fields,
+			})
+		}
+		if errors.Is(err, port.ErrIdempotencyKeyReused) || errors.Is(err, port.ErrIdempotencyKeyExists) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+		}
+		if errors.Is(err, port.ErrInvalidStartTime) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error":   "Failed to create workflow",
 			"details": err.Error(),
@@ -99,11 +125,92 @@ func (h *workflowHandler) GetWorkflowDetail(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
 	}
 
-	// 4. ส่งกลับไปพร้อมกัน
+	// 4. ผลลัพธ์ของ workflow (output ของ task สุดท้าย)
+	var output json.RawMessage
+	if wf.CurrentOutput != nil {
+		output = json.RawMessage(*wf.CurrentOutput)
+	}
+	// State สะสม: input + output ของทุก step
+	var state json.RawMessage
+	if wf.State != nil {
+		state = json.RawMessage(*wf.State)
+	}
+
+	// 5. Parent / child workflows
+	var parent *model.WorkflowInstances
+	if wf.ParentID != nil {
+		parent, err = h.svc.GetWorkflowByID(ctx, *wf.ParentID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+		}
+	}
+	children, err := h.svc.GetChildWorkflows(ctx, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+	}
+
+	// 6. ส่งกลับไปพร้อมกัน
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"workflow":     wf,
+		"output":       output,
+		"state":        state,
 		"tasks":        tasks,
 		"activityLogs": logs,
+		"parent":       parent,
+		"children":     children,
+	})
+}
+
+// POST /workflows/:id/cancel
+func (h *workflowHandler) CancelWorkflow(c echo.Context) error {
+	id := c.Param("id")
+
+	if err := h.svc.CancelWorkflow(c.Request().Context(), id); err != nil {
+		if errors.Is(err, port.ErrWorkflowNotActive) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":   "Failed to cancel workflow",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Workflow cancelled successfully",
+	})
+}
+
+// POST /workflows/:id/signals/:name
+func (h *workflowHandler) SendSignal(c echo.Context) error {
+	id := c.Param("id")
+	name := c.Param("name")
+
+	// Body (optional) เป็น payload ของ signal
+	var payload map[string]any
+	if c.Request().ContentLength != 0 {
+		if err := json.NewDecoder(c.Request().Body).Decode(&payload); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	if err := h.svc.SendSignal(c.Request().Context(), id, name, payload); err != nil {
+		if errors.Is(err, port.ErrWorkflowNotActive) {
+			return c.JSON(http.StatusConflict, map[string]interface{}{"error": err.Error()})
+		}
+		if errors.Is(err, port.ErrUnknownSignal) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":   "Failed to send signal",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Signal sent successfully",
 	})
 }
 
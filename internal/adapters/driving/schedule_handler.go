@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+)
+
+type scheduleHandler struct {
+	svc       port.ScheduleService
+	validator *validator.Validate
+}
+
+func NewScheduleHandler(svc port.ScheduleService) *scheduleHandler {
+	return &scheduleHandler{
+		svc:       svc,
+		validator: validator.New(),
+	}
+}
+
+// bindScheduleRequest reads and validates a create/update body. It writes the
+// 400 response itself and returns nil when the body is invalid
+func (h *scheduleHandler) bindScheduleRequest(c echo.Context) (*port.ScheduleRequest, error) {
+	req := &port.ScheduleRequest{}
+
+	if err := c.Bind(req); err != nil {
+		return nil, c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		validationErrors := make(map[string]string)
+		for _, err := range err.(validator.ValidationErrors) {
+			field := err.Field()
+			switch err.Tag() {
+			case "required":
+				validationErrors[field] = field + " is required"
+			case "min":
+				validationErrors[field] = field + " must be at least " + err.Param() + " characters"
+			case "max":
+				validationErrors[field] = field + " must be at most " + err.Param() + " characters"
+			case "oneof":
+				validationErrors[field] = field + " must be one of: " + err.Param()
+			default:
+				validationErrors[field] = field + " is invalid"
+			}
+		}
+		return nil, c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Validation failed",
+			"fields": validationErrors,
+		})
+	}
+
+	return req, nil
+}
+
+// scheduleError maps service errors to a response
+func scheduleError(c echo.Context, err error, message string) error {
+	if errors.Is(err, port.ErrScheduleNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+	}
+	var inputErr *port.InputValidationError
+	if errors.As(err, &inputErr) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Validation failed",
+			"fields": inputErr.Fields,
+		})
+	}
+	if errors.Is(err, port.ErrInvalidSchedule) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+// POST /schedules
+func (h *scheduleHandler) CreateSchedule(c echo.Context) error {
+	req, err := h.bindScheduleRequest(c)
+	if req == nil {
+		return err
+	}
+
+	schedule, err := h.svc.CreateSchedule(c.Request().Context(), req)
+	if err != nil {
+		return scheduleError(c, err, "Failed to create schedule")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "Schedule created successfully",
+		"data":    schedule,
+	})
+}
+
+// GET /schedules
+func (h *scheduleHandler) ListSchedules(c echo.Context) error {
+	limit := 20
+	offset := 0
+
+	if l := c.QueryParam("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	if o := c.QueryParam("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	schedules, err := h.svc.ListSchedules(c.Request().Context(), limit, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"schedules": schedules,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// GET /schedules/:id
+func (h *scheduleHandler) GetSchedule(c echo.Context) error {
+	schedule, err := h.svc.GetSchedule(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return scheduleError(c, err, "Failed to get schedule")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"data": schedule})
+}
+
+// PUT /schedules/:id
+func (h *scheduleHandler) UpdateSchedule(c echo.Context) error {
+	req, err := h.bindScheduleRequest(c)
+	if req == nil {
+		return err
+	}
+
+	schedule, err := h.svc.UpdateSchedule(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return scheduleError(c, err, "Failed to update schedule")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Schedule updated successfully",
+		"data":    schedule,
+	})
+}
+
+// DELETE /schedules/:id
+func (h *scheduleHandler) DeleteSchedule(c echo.Context) error {
+	if err := h.svc.DeleteSchedule(c.Request().Context(), c.Param("id")); err != nil {
+		return scheduleError(c, err, "Failed to delete schedule")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Schedule deleted successfully",
+	})
+}
+
+// POST /schedules/:id/pause
+func (h *scheduleHandler) PauseSchedule(c echo.Context) error {
+	schedule, err := h.svc.PauseSchedule(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return scheduleError(c, err, "Failed to pause schedule")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Schedule paused successfully",
+		"data":    schedule,
+	})
+}
+
+// POST /schedules/:id/resume
+func (h *scheduleHandler) ResumeSchedule(c echo.Context) error {
+	schedule, err := h.svc.ResumeSchedule(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return scheduleError(c, err, "Failed to resume schedule")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Schedule resumed successfully",
+		"data":    schedule,
+	})
+}
@@ -0,0 +1,52 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+)
+
+// ScheduleRequest creates or replaces a cron schedule
+type ScheduleRequest struct {
+	Name           string         `json:"name" validate:"required,min=3,max=100"`
+	WorkflowName   string         `json:"workflow_name" validate:"required,min=3,max=100"`
+	CronExpression string         `json:"cron_expression" validate:"required"`
+	TimeZone       string         `json:"time_zone"`
+	InputPayload   map[string]any `json:"input_payload"`
+	OverlapPolicy  string         `json:"overlap_policy" validate:"omitempty,oneof=SKIP ALLOW BUFFER_ONE"`
+	Paused         bool           `json:"paused"`
+}
+
+// ErrScheduleNotFound is returned for an unknown schedule ID
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ErrInvalidSchedule wraps a bad cron expression, time zone or workflow name
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ScheduleRepository persists cron schedules. Its calls join a transaction
+// started with WorkflowRepository.WithTx on the same database
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *model.WorkflowSchedules) error
+	GetScheduleByID(ctx context.Context, id string) (*model.WorkflowSchedules, error)
+	ListSchedules(ctx context.Context, limit int, offset int) ([]model.WorkflowSchedules, error)
+	UpdateSchedule(ctx context.Context, schedule *model.WorkflowSchedules) error
+	DeleteSchedule(ctx context.Context, id string) (bool, error)
+	GetDueScheduleIDs(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// LockDueSchedule locks a schedule that is still due, skipping rows another
+	// replica holds; it returns nil when there is nothing to fire
+	LockDueSchedule(ctx context.Context, id string, now time.Time) (*model.WorkflowSchedules, error)
+	UpdateScheduleRun(ctx context.Context, schedule *model.WorkflowSchedules) error
+	RecordScheduleFailure(ctx context.Context, schedule *model.WorkflowSchedules) error
+}
+
+type ScheduleService interface {
+	CreateSchedule(ctx context.Context, req *ScheduleRequest) (*model.WorkflowSchedules, error)
+	ListSchedules(ctx context.Context, limit int, offset int) ([]model.WorkflowSchedules, error)
+	GetSchedule(ctx context.Context, id string) (*model.WorkflowSchedules, error)
+	UpdateSchedule(ctx context.Context, id string, req *ScheduleRequest) (*model.WorkflowSchedules, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	PauseSchedule(ctx context.Context, id string) (*model.WorkflowSchedules, error)
+	ResumeSchedule(ctx context.Context, id string) (*model.WorkflowSchedules, error)
+}
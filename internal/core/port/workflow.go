@@ -2,6 +2,10 @@ package port
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
 )
@@ -9,28 +13,111 @@ import (
 type CreateWorkflowRequest struct {
 	WorkflowName string         `json:"workflow_name" validate:"required,min=3,max=100"`
 	InputPayload map[string]any `json:"input_payload"`
+
+	// Optional delayed start: an absolute time or a duration from now (e.g. "2h"), not both
+	StartAt *time.Time `json:"start_at"`
+	Delay   string     `json:"delay"`
+
+	// Optional client key (or Idempotency-Key header): a retried request with
+	// the same key returns the instance created by the first one
+	IdempotencyKey string `json:"idempotency_key" validate:"omitempty,max=255"`
+
+	// Set by the worker when a child workflow step starts an instance
+	ParentID     *string `json:"-"`
+	ParentTaskID *int64  `json:"-"`
+}
+
+// ErrTaskAlreadyExists is returned by CreateTask when the workflow instance
+// already has a task with that name
+var ErrTaskAlreadyExists = errors.New("task already exists for workflow")
+
+// ErrWorkflowNotActive is returned when cancelling or signalling a workflow
+// that already finished
+var ErrWorkflowNotActive = errors.New("workflow is not active")
+
+// ErrIdempotencyKeyExists is returned by CreateWorkflow when another instance
+// already has the idempotency key
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is sent again
+// with a different request body
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+// ErrInvalidStartTime is returned for a request with both start_at and delay,
+// or a delay that is not a valid positive duration
+var ErrInvalidStartTime = errors.New("invalid start time")
+
+// InputValidationError is returned when an input payload does not match the
+// input schema of its workflow. Fields maps each invalid field to a message
+type InputValidationError struct {
+	Fields map[string]string
+}
+
+func (e *InputValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return "invalid input payload: " + strings.Join(fields, ", ")
 }
 
+// ErrUnknownSignal is returned when a workflow has no step waiting for a signal
+var ErrUnknownSignal = errors.New("workflow does not wait for this signal")
+
 type WorkflowRepository interface {
+	// WithTx runs fn in one transaction; repository calls made with the ctx
+	// passed to fn are part of it
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
 	// Workflow operation
 	CreateWorkflow(ctx context.Context, workflow *model.WorkflowInstances) error
 	GetWorkflowPending(ctx context.Context, limit int) ([]model.WorkflowInstances, error)
 	ListWorkflows(ctx context.Context, limit int, offset int) ([]model.WorkflowInstances, error)
 	CountWorkflows(ctx context.Context) (int64, error)
 	UpdateWorkflowStatus(ctx context.Context, id string, status string) error
+	TransitionWorkflowStatus(ctx context.Context, id string, status string, from ...string) (bool, error)
+	GetTimedOutWorkflows(ctx context.Context, limit int) ([]model.WorkflowInstances, error)
+	UpdateWorkflowOutput(ctx context.Context, id string, output string) error
+	UpdateWorkflowState(ctx context.Context, id string, state string) error
 	GetWorkflowByID(cxt context.Context, id string) (*model.WorkflowInstances, error)
+	// GetWorkflowByIdempotencyKey returns nil when no instance has the key
+	GetWorkflowByIdempotencyKey(ctx context.Context, key string) (*model.WorkflowInstances, error)
+	// LockWorkflow is GetWorkflowByID with a row lock held until the transaction ends
+	LockWorkflow(ctx context.Context, id string) (*model.WorkflowInstances, error)
+	GetChildWorkflows(ctx context.Context, parentID string) ([]model.WorkflowInstances, error)
 
 	// Task operation
 	CreateTask(ctx context.Context, workflow *model.Tasks) error
 	GetTasksByWorkflowID(ctx context.Context, wfID string) ([]model.Tasks, error)
-	GetTaskPending(ctx context.Context, limit int) ([]model.Tasks, error)
+	ClaimTasks(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Tasks, error)
+	RenewTaskLease(ctx context.Context, id int, workerID string, lease time.Duration) (bool, error)
+	GetExpiredTasks(ctx context.Context, limit int) ([]model.Tasks, error)
+	ReleaseExpiredTask(ctx context.Context, task model.Tasks, status string, retryCount int) (bool, error)
+	ReleaseTaskClaims(ctx context.Context, workerID string) (int64, error)
 	UpdateTaskStatus(ctx context.Context, id int, status string) error
-	UpdateTaskRetryCount(ctx context.Context, id int, retryCount int) error
-	GetTasksForRetry(ctx context.Context, limit int) ([]model.Tasks, error)
+	UpdatePendingTasksStatus(ctx context.Context, wfID string, status string) error
+	ParkTask(ctx context.Context, id int, workerID string) (bool, error)
+	WakeTask(ctx context.Context, id int) (bool, error)
+	DeferTask(ctx context.Context, id int, workerID string, until time.Time) (bool, error)
+	ResumeTask(ctx context.Context, id int) (bool, error)
+	UpdateTaskErrorMessage(ctx context.Context, id int, message string) error
+	CompleteTask(ctx context.Context, id int, workerID string, output *string) (bool, error)
+	// ScheduleTaskRetry, FailTask and MarkTaskRetrying only touch a task
+	// workerID still holds; they return false once the claim is gone
+	ScheduleTaskRetry(ctx context.Context, id int, workerID string, retryCount int, scheduledAt time.Time) (bool, error)
+	FailTask(ctx context.Context, id int, workerID string) (bool, error)
+	MarkTaskRetrying(ctx context.Context, id int, workerID string) (bool, error)
 
 	// Activity Log operation
 	CreateActivityLog(ctx context.Context, log *model.ActivityLogs) error
 	GetActivityLogsByWorkflowID(ctx context.Context, wfID string) ([]model.ActivityLogs, error)
+
+	// Signal operation
+	CreateSignal(ctx context.Context, signal *model.WorkflowSignals) error
+	GetSignalForTask(ctx context.Context, wfID string, name string, taskID int64) (*model.WorkflowSignals, error)
+	ConsumeSignal(ctx context.Context, id int64, taskID int64) error
 }
 
 type WorkflowService interface {
@@ -40,5 +127,8 @@ type WorkflowService interface {
 	GetWorkflowByID(ctx context.Context, id string) (*model.WorkflowInstances, error)
 	GetTasksByWorkflowID(ctx context.Context, wfID string) ([]model.Tasks, error)
 	GetActivityLogsByWorkflowID(ctx context.Context, wfID string) ([]model.ActivityLogs, error)
+	GetChildWorkflows(ctx context.Context, parentID string) ([]model.WorkflowInstances, error)
+	CancelWorkflow(ctx context.Context, id string) error
+	SendSignal(ctx context.Context, id string, name string, payload map[string]any) error
 	ListAvailableWorkflows(ctx context.Context) []string
 }
@@ -0,0 +1,40 @@
+package registry
+
+import "errors"
+
+// RegisterActivity adds a named task function to the activity catalog, so
+// declarative workflows (see LoadDefinitions) can use it as a step
+func (r *WorkflowRegistry) RegisterActivity(name string, fn TaskFunc) error {
+	if name == "" {
+		return errors.New("activity name cannot be empty")
+	}
+	if fn == nil {
+		return errors.New("activity function not defined: " + name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.activities[name]; exists {
+		return errors.New("activity already registered: " + name)
+	}
+	r.activities[name] = fn
+
+	return nil
+}
+
+// MustRegisterActivity registers an activity and panics on error
+func (r *WorkflowRegistry) MustRegisterActivity(name string, fn TaskFunc) {
+	if err := r.RegisterActivity(name, fn); err != nil {
+		panic("failed to register activity: " + err.Error())
+	}
+}
+
+// GetActivity retrieves an activity from the catalog
+func (r *WorkflowRegistry) GetActivity(name string) (TaskFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, exists := r.activities[name]
+	return fn, exists
+}
@@ -0,0 +1,99 @@
+package registry
+
+import "fmt"
+
+// End is a branch target that ends the current path instead of starting a task
+const End = "__end__"
+
+// BranchPredicate decides a branch from the output payload of the task
+// the branch is attached to
+type BranchPredicate func(output map[string]any) bool
+
+// BranchCase selects Next when Predicate matches. A nil Predicate always
+// matches
+type BranchCase struct {
+	Next      string
+	Predicate BranchPredicate
+}
+
+// When selects next when pred matches the task's output
+func When(next string, pred BranchPredicate) BranchCase {
+	return BranchCase{Next: next, Predicate: pred}
+}
+
+// Otherwise selects next when no earlier case matched
+func Otherwise(next string) BranchCase {
+	return BranchCase{Next: next}
+}
+
+// Branch attaches cases to the last added task. After the task completes the
+// first matching case picks the next task (or End); the other targets are
+// skipped. When no case matches the path ends. Dependents of the task that
+// are not branch targets still start as usual
+func (b *WorkflowBuilder) Branch(cases ...BranchCase) *WorkflowBuilder {
+	if len(b.taskNames) == 0 {
+		b.err = fmt.Errorf("branch must follow a task")
+		return b
+	}
+
+	task := b.tasks[b.taskNames[len(b.taskNames)-1]]
+	task.Branches = append(task.Branches, cases...)
+
+	return b
+}
+
+// SelectBranch returns the target of the first case matching output
+func (t *TaskDefinition) SelectBranch(output map[string]any) (next string, index int, matched bool) {
+	for i, c := range t.Branches {
+		if c.Predicate == nil || c.Predicate(output) {
+			return c.Next, i, true
+		}
+	}
+
+	return "", -1, false
+}
+
+// RouteTargets returns the tasks this task can route to instead of its
+// regular dependents: its branch targets and its signal timeout fallback
+func (t *TaskDefinition) RouteTargets() []string {
+	targets := []string{}
+	for _, c := range t.Branches {
+		targets = append(targets, c.Next)
+	}
+	if t.Signal != nil && t.Signal.Fallback != "" {
+		targets = append(targets, t.Signal.Fallback)
+	}
+
+	return targets
+}
+
+// IsRouteTarget reports whether taskName is one of the task's route targets
+func (t *TaskDefinition) IsRouteTarget(taskName string) bool {
+	for _, target := range t.RouteTargets() {
+		if target == taskName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateRoutes checks that every branch target and signal fallback is a
+// known task (or End)
+func validateRoutes(def *WorkflowDefinition) error {
+	for _, name := range def.TaskNames {
+		for _, target := range def.Tasks[name].RouteTargets() {
+			if target == End {
+				continue
+			}
+			if target == name {
+				return fmt.Errorf("task %s routes to itself", name)
+			}
+			if _, exists := def.Tasks[target]; !exists {
+				return fmt.Errorf("task %s routes to unknown task: %s", name, target)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AddChildWorkflow adds a step that starts workflowName as a child instance
+// with the step's input and waits for it. The step completes with the
+// child's output, and fails if the child fails or is cancelled
+func (b *WorkflowBuilder) AddChildWorkflow(taskName, workflowName string, opts ...TaskOption) *WorkflowBuilder {
+	b.AddTask(taskName, nil, opts...)
+	b.tasks[taskName].ChildWorkflow = workflowName
+
+	return b
+}
+
+// validateChildCycles rejects def if its child workflow steps lead back to
+// def itself through already registered workflows (A -> B -> A), which would
+// start instances without end. Every registered version counts, as instances
+// still running on an older one start children too. r.mu must be held
+func (r *WorkflowRegistry) validateChildCycles(def *WorkflowDefinition) error {
+	children := func(name string) []string {
+		defs := []*WorkflowDefinition{}
+		if name == def.Name {
+			defs = append(defs, def)
+		}
+		for _, version := range r.versions[name] {
+			defs = append(defs, version)
+		}
+
+		names := []string{}
+		for _, d := range defs {
+			for _, taskName := range d.TaskNames {
+				if child := d.Tasks[taskName].ChildWorkflow; child != "" {
+					names = append(names, child)
+				}
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// Depth-first search from def; the registry had no cycle before, so any
+	// new one runs through def
+	visited := map[string]bool{}
+	path := []string{def.Name}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		for _, child := range children(name) {
+			if child == def.Name {
+				return fmt.Errorf("child workflow cycle: %s -> %s", strings.Join(path, " -> "), child)
+			}
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+
+			path = append(path, child)
+			if err := visit(child); err != nil {
+				return err
+			}
+			path = path[:len(path)-1]
+		}
+
+		return nil
+	}
+
+	return visit(def.Name)
+}
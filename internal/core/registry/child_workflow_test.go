@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterChildWorkflowCycles(t *testing.T) {
+	reg := NewWorkflowRegistry()
+
+	// A -> B and B -> C are fine; registering C -> A closes A -> B -> C -> A
+	if err := reg.NewWorkflow("A").AddChildWorkflow("RunB", "B").Build(); err != nil {
+		t.Fatalf("register A: %v", err)
+	}
+	if err := reg.NewWorkflow("B").AddChildWorkflow("RunC", "C").Build(); err != nil {
+		t.Fatalf("register B: %v", err)
+	}
+
+	err := reg.NewWorkflow("C").AddTask("Work", noop).AddChildWorkflow("RunA", "A").Build()
+	if err == nil || !strings.Contains(err.Error(), "child workflow cycle: C -> A -> B -> C") {
+		t.Fatalf("register C error = %v, want child workflow cycle", err)
+	}
+	if _, ok := reg.GetDefinition("C"); ok {
+		t.Error("C registered despite the cycle")
+	}
+
+	// A C that does not start A is accepted
+	if err := reg.NewWorkflow("C").AddTask("Work", noop).Build(); err != nil {
+		t.Fatalf("register C without child: %v", err)
+	}
+}
+
+func TestRegisterChildWorkflowCycleThroughOlderVersion(t *testing.T) {
+	reg := NewWorkflowRegistry()
+
+	if err := reg.NewWorkflow("A").AddChildWorkflow("RunB", "B").Build(); err != nil {
+		t.Fatalf("register A v1: %v", err)
+	}
+	if err := reg.NewWorkflow("A").Version(2).AddTask("Work", noop).Build(); err != nil {
+		t.Fatalf("register A v2: %v", err)
+	}
+
+	// Instances still running A v1 start B
+	err := reg.NewWorkflow("B").AddChildWorkflow("RunA", "A").Build()
+	if err == nil || !strings.Contains(err.Error(), "child workflow cycle") {
+		t.Fatalf("register B error = %v, want child workflow cycle", err)
+	}
+}
+
+func TestRegisterChildWorkflowSelf(t *testing.T) {
+	err := NewWorkflowRegistry().NewWorkflow("A").AddChildWorkflow("RunA", "A").Build()
+	if err == nil {
+		t.Fatal("workflow starting itself as a child was registered")
+	}
+}
+
+func TestRegisterChildWorkflowShared(t *testing.T) {
+	reg := NewWorkflowRegistry()
+
+	// A diamond of children is not a cycle
+	if err := reg.NewWorkflow("Leaf").AddTask("Work", noop).Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.NewWorkflow("Left").AddChildWorkflow("RunLeaf", "Leaf").Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.NewWorkflow("Right").AddChildWorkflow("RunLeaf", "Leaf").Build(); err != nil {
+		t.Fatal(err)
+	}
+	err := reg.NewWorkflow("Top").
+		AddChildWorkflow("RunLeft", "Left").
+		AddChildWorkflow("RunRight", "Right").
+		Build()
+	if err != nil {
+		t.Fatalf("register Top: %v", err)
+	}
+}
+
+func TestRegisterTogetherRollsBackCycle(t *testing.T) {
+	reg := NewWorkflowRegistry()
+
+	a, _ := reg.NewWorkflow("A").AddChildWorkflow("RunB", "B").definition()
+	b, _ := reg.NewWorkflow("B").AddChildWorkflow("RunA", "A").definition()
+
+	// B closes the cycle against A from the same batch; A is removed again
+	err := reg.register(a, b)
+	if err == nil || !strings.Contains(err.Error(), "child workflow cycle: B -> A -> B") {
+		t.Fatalf("register error = %v, want child workflow cycle", err)
+	}
+	for _, name := range []string{"A", "B"} {
+		if _, ok := reg.GetDefinition(name); ok {
+			t.Errorf("%s registered although the batch failed", name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package registry
+
+import "strings"
+
+// compensationPrefix marks the task rows that run a step's compensation
+const compensationPrefix = "compensate:"
+
+// WithCompensation sets the function that undoes a completed task when the
+// workflow fails permanently (saga). It receives the task's output payload
+// as input
+func WithCompensation(fn TaskFunc) TaskOption {
+	return func(t *TaskDefinition) {
+		t.Compensation = fn
+	}
+}
+
+// CompensationTaskName returns the task name used to compensate taskName
+func CompensationTaskName(taskName string) string {
+	return compensationPrefix + taskName
+}
+
+// CompensatedTaskName returns the task a compensation task undoes
+func CompensatedTaskName(taskName string) (string, bool) {
+	return strings.CutPrefix(taskName, compensationPrefix)
+}
+
+// CompensationFunc retrieves the compensation function of a task (of its
+// map step for map items)
+func (d *WorkflowDefinition) CompensationFunc(taskName string) (TaskFunc, bool) {
+	task, exists := d.Step(taskName)
+	if !exists || task.Compensation == nil {
+		return nil, false
+	}
+
+	return task.Compensation, true
+}
@@ -0,0 +1,118 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependsOn declares the tasks that must complete before a task starts.
+// Without it a task depends on the task added just before it; DependsOn()
+// with no names makes the task a root that starts with the workflow
+func DependsOn(taskNames ...string) TaskOption {
+	return func(t *TaskDefinition) {
+		// non-nil even when empty, so AddTask can tell "no dependencies"
+		// apart from "not set"
+		t.DependsOn = append([]string{}, taskNames...)
+	}
+}
+
+// RootTasks returns the tasks without dependencies, in definition order
+func (d *WorkflowDefinition) RootTasks() []string {
+	roots := []string{}
+	for _, name := range d.TaskNames {
+		if len(d.Tasks[name].DependsOn) == 0 {
+			roots = append(roots, name)
+		}
+	}
+
+	return roots
+}
+
+// NextTasks returns the tasks that depend on taskName, in definition order
+func (d *WorkflowDefinition) NextTasks(taskName string) []string {
+	next := []string{}
+	for _, name := range d.TaskNames {
+		for _, dep := range d.Tasks[name].DependsOn {
+			if dep == taskName {
+				next = append(next, name)
+				break
+			}
+		}
+	}
+
+	return next
+}
+
+// validateDAG checks that task names are unique, every dependency exists and
+// the dependencies (including route edges) do not form a cycle
+func validateDAG(def *WorkflowDefinition) error {
+	seen := make(map[string]bool, len(def.TaskNames))
+	for _, name := range def.TaskNames {
+		if seen[name] {
+			return fmt.Errorf("duplicate task: %s", name)
+		}
+		seen[name] = true
+	}
+
+	for _, name := range def.TaskNames {
+		for _, dep := range def.Tasks[name].DependsOn {
+			if !seen[dep] {
+				return fmt.Errorf("task %s depends on unknown task: %s", name, dep)
+			}
+		}
+	}
+
+	// A route target runs after the task that routes to it
+	deps := make(map[string][]string, len(def.TaskNames))
+	for _, name := range def.TaskNames {
+		deps[name] = append(deps[name], def.Tasks[name].DependsOn...)
+		for _, target := range def.Tasks[name].RouteTargets() {
+			if target != End {
+				deps[target] = append(deps[target], name)
+			}
+		}
+	}
+
+	// Depth-first search; a task met again while still on the stack closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(def.TaskNames))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), name)
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+
+		return nil
+	}
+
+	for _, name := range def.TaskNames {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	if len(def.RootTasks()) == 0 {
+		return fmt.Errorf("workflow has no root task")
+	}
+
+	return nil
+}
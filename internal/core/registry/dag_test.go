@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+)
+
+func noop(ctx context.Context, task *model.Tasks) error {
+	return nil
+}
+
+// dagDef builds a definition from task name -> dependencies, in the given order
+func dagDef(names []string, deps map[string][]string) *WorkflowDefinition {
+	def := &WorkflowDefinition{Name: "Test", TaskNames: names, Tasks: map[string]*TaskDefinition{}}
+	for _, name := range names {
+		def.Tasks[name] = &TaskDefinition{Name: name, Func: noop, DependsOn: deps[name]}
+	}
+	return def
+}
+
+func TestValidateDAG(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		deps    map[string][]string
+		wantErr string
+	}{
+		{
+			name:  "sequential",
+			names: []string{"A", "B", "C"},
+			deps:  map[string][]string{"B": {"A"}, "C": {"B"}},
+		},
+		{
+			name:  "diamond",
+			names: []string{"A", "B", "C", "D"},
+			deps:  map[string][]string{"B": {"A"}, "C": {"A"}, "D": {"B", "C"}},
+		},
+		{
+			name:  "several roots",
+			names: []string{"A", "B", "C"},
+			deps:  map[string][]string{"C": {"A", "B"}},
+		},
+		{
+			name:    "duplicate task",
+			names:   []string{"A", "A"},
+			wantErr: "duplicate task: A",
+		},
+		{
+			name:    "unknown dependency",
+			names:   []string{"A", "B"},
+			deps:    map[string][]string{"B": {"Missing"}},
+			wantErr: "task B depends on unknown task: Missing",
+		},
+		{
+			name:    "self dependency",
+			names:   []string{"A", "B"},
+			deps:    map[string][]string{"B": {"B"}},
+			wantErr: "dependency cycle",
+		},
+		{
+			name:    "cycle",
+			names:   []string{"Root", "A", "B", "C"},
+			deps:    map[string][]string{"A": {"Root", "C"}, "B": {"A"}, "C": {"B"}},
+			wantErr: "dependency cycle",
+		},
+		{
+			name:    "no root",
+			names:   []string{"A", "B"},
+			deps:    map[string][]string{"A": {"B"}, "B": {"A"}},
+			wantErr: "dependency cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDAG(dagDef(tt.names, tt.deps))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateDAG: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateDAG error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDAGRouteCycle(t *testing.T) {
+	// B routes back to A, which B depends on
+	def := dagDef([]string{"A", "B"}, map[string][]string{"B": {"A"}})
+	def.Tasks["B"].Branches = []BranchCase{Otherwise("A")}
+
+	err := validateDAG(def)
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Fatalf("validateDAG error = %v, want dependency cycle", err)
+	}
+}
+
+func TestRootAndNextTasks(t *testing.T) {
+	def := dagDef([]string{"A", "B", "C", "D"}, map[string][]string{"B": {"A"}, "C": {"A"}, "D": {"B", "C"}})
+
+	if got, want := def.RootTasks(), []string{"A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RootTasks = %v, want %v", got, want)
+	}
+	if got, want := def.NextTasks("A"), []string{"B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NextTasks(A) = %v, want %v", got, want)
+	}
+	if got := def.NextTasks("D"); len(got) != 0 {
+		t.Errorf("NextTasks(D) = %v, want none", got)
+	}
+}
+
+func TestBuilderSequentialByDefault(t *testing.T) {
+	reg := NewWorkflowRegistry()
+	err := reg.NewWorkflow("Seq").
+		AddTask("A", noop).
+		AddTask("B", noop).
+		AddTask("C", noop, DependsOn()).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	def, _ := reg.GetDefinition("Seq")
+	if got, want := def.RootTasks(), []string{"A", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RootTasks = %v, want %v", got, want)
+	}
+	if got, want := def.Tasks["B"].DependsOn, []string{"A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("B.DependsOn = %v, want %v", got, want)
+	}
+}
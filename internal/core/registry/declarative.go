@@ -0,0 +1,346 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"gopkg.in/yaml.v3"
+)
+
+// statePathPrefix starts an input mapping that reads from the workflow state
+const statePathPrefix = "$."
+
+// DeclarativeWorkflow is a workflow definition loaded from a YAML or JSON file
+type DeclarativeWorkflow struct {
+	Name    string            `yaml:"name"`
+	Version int               `yaml:"version"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Steps   []DeclarativeStep `yaml:"steps"`
+}
+
+// DeclarativeStep is one step of a declarative workflow, running an
+// activity from the catalog
+type DeclarativeStep struct {
+	Name         string            `yaml:"name"`
+	Activity     string            `yaml:"activity"`
+	DependsOn    []string          `yaml:"depends_on"`
+	Timeout      time.Duration     `yaml:"timeout"`
+	Retry        *DeclarativeRetry `yaml:"retry"`
+	Compensation string            `yaml:"compensation"`
+	// Input builds the step input from the workflow state: string values
+	// starting with "$." are paths ($.input.x, $.steps.Task.x), others literals
+	Input map[string]any `yaml:"input"`
+}
+
+// DeclarativeRetry is the retry policy of a declarative step
+type DeclarativeRetry struct {
+	MaxAttempts            int           `yaml:"max_attempts"`
+	InitialInterval        time.Duration `yaml:"initial_interval"`
+	MaxInterval            time.Duration `yaml:"max_interval"`
+	BackoffCoefficient     float64       `yaml:"backoff_coefficient"`
+	Jitter                 float64       `yaml:"jitter"`
+	NonRetryableErrorTypes []string      `yaml:"non_retryable_error_types"`
+}
+
+// ParseDeclarativeWorkflow decodes a workflow definition. JSON is accepted
+// as it is valid YAML; unknown fields are rejected
+func ParseDeclarativeWorkflow(data []byte) (*DeclarativeWorkflow, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	wf := &DeclarativeWorkflow{}
+	if err := decoder.Decode(wf); err != nil {
+		return nil, err
+	}
+
+	return wf, nil
+}
+
+// LoadDefinitions registers every workflow defined in the .yaml, .yml and
+// .json files of dir. Either every file is registered or, on the first
+// error, none is. A missing dir loads nothing
+func (r *WorkflowRegistry) LoadDefinitions(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defs := []*WorkflowDefinition{}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		wf, err := ParseDeclarativeWorkflow(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		def, err := r.declarativeDefinition(wf)
+		if err == nil {
+			err = validateDefinition(def)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defs = append(defs, def)
+	}
+
+	// Duplicates and child workflow cycles show up only against the
+	// registry; register every file or none
+	if err := r.register(defs...); err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+
+	names := make([]string, 0, len(defs))
+	for _, def := range defs {
+		names = append(names, def.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// RegisterDeclarative builds a declarative workflow from the activity
+// catalog and registers it like a code-defined one
+func (r *WorkflowRegistry) RegisterDeclarative(wf *DeclarativeWorkflow) error {
+	def, err := r.declarativeDefinition(wf)
+	if err != nil {
+		return err
+	}
+
+	return r.Register(def)
+}
+
+// declarativeDefinition builds a declarative workflow without registering it
+func (r *WorkflowRegistry) declarativeDefinition(wf *DeclarativeWorkflow) (*WorkflowDefinition, error) {
+	if err := r.validateDeclarative(wf); err != nil {
+		return nil, err
+	}
+
+	b := r.NewWorkflow(wf.Name).Version(wf.Version).Timeout(wf.Timeout)
+	for _, step := range wf.Steps {
+		fn, _ := r.GetActivity(step.Activity)
+		if len(step.Input) > 0 {
+			fn = withInputMapping(fn, step.Input)
+		}
+
+		opts := []TaskOption{}
+		if step.DependsOn != nil {
+			opts = append(opts, DependsOn(step.DependsOn...))
+		}
+		if step.Timeout > 0 {
+			opts = append(opts, WithTimeout(step.Timeout))
+		}
+		if step.Retry != nil {
+			opts = append(opts, WithRetryPolicy(RetryPolicy{
+				MaxAttempts:            step.Retry.MaxAttempts,
+				InitialInterval:        step.Retry.InitialInterval,
+				MaxInterval:            step.Retry.MaxInterval,
+				BackoffCoefficient:     step.Retry.BackoffCoefficient,
+				Jitter:                 step.Retry.Jitter,
+				NonRetryableErrorTypes: step.Retry.NonRetryableErrorTypes,
+			}))
+		}
+		if step.Compensation != "" {
+			compensation, _ := r.GetActivity(step.Compensation)
+			opts = append(opts, WithCompensation(compensation))
+		}
+
+		b.AddTask(step.Name, fn, opts...)
+	}
+
+	return b.definition()
+}
+
+// validateDeclarative checks a declarative workflow against the activity
+// catalog. The step graph itself is checked by Register
+func (r *WorkflowRegistry) validateDeclarative(wf *DeclarativeWorkflow) error {
+	if wf.Name == "" {
+		return errors.New("workflow name cannot be empty")
+	}
+	if len(wf.Steps) == 0 {
+		return fmt.Errorf("workflow %s has no steps", wf.Name)
+	}
+
+	steps := map[string]bool{}
+	for _, step := range wf.Steps {
+		steps[step.Name] = true
+	}
+
+	for _, step := range wf.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("workflow %s has a step without a name", wf.Name)
+		}
+		if _, exists := r.GetActivity(step.Activity); !exists {
+			return fmt.Errorf("step %s uses unknown activity: %q", step.Name, step.Activity)
+		}
+		if step.Compensation != "" {
+			if _, exists := r.GetActivity(step.Compensation); !exists {
+				return fmt.Errorf("step %s uses unknown compensation activity: %q", step.Name, step.Compensation)
+			}
+		}
+		if err := validateMapping(step.Input, steps); err != nil {
+			return fmt.Errorf("step %s input: %w", step.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateMapping checks that every state path of an input mapping reads
+// the input or an existing step
+func validateMapping(value any, steps map[string]bool) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, field := range v {
+			if err := validateMapping(field, steps); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, field := range v {
+			if err := validateMapping(field, steps); err != nil {
+				return err
+			}
+		}
+	case string:
+		path, isPath := strings.CutPrefix(v, statePathPrefix)
+		if !isPath {
+			return nil
+		}
+		segments := strings.Split(path, ".")
+		switch {
+		case segments[0] == "input":
+		case segments[0] == "steps" && len(segments) > 1:
+			if !steps[segments[1]] {
+				return fmt.Errorf("%s reads unknown step %s", v, segments[1])
+			}
+		default:
+			return fmt.Errorf("%s must start with $.input or $.steps.<Task>", v)
+		}
+	}
+
+	return nil
+}
+
+// withInputMapping runs fn with its input built from the workflow state
+func withInputMapping(fn TaskFunc, mapping map[string]any) TaskFunc {
+	return func(ctx context.Context, task *model.Tasks) error {
+		state, ok := StateFromContext(ctx)
+		if !ok {
+			return errors.New("input mapping needs the workflow state")
+		}
+
+		input, err := evaluateMapping(mapping, state)
+		if err != nil {
+			return NonRetryable(fmt.Errorf("input mapping: %w", err))
+		}
+		inputJSON, err := json.Marshal(input)
+		if err != nil {
+			return NonRetryable(fmt.Errorf("input mapping: %w", err))
+		}
+		inputStr := string(inputJSON)
+
+		// The stored input of the task row is left as it was
+		original := task.InputPayload
+		task.InputPayload = &inputStr
+		defer func() { task.InputPayload = original }()
+
+		return fn(ctx, task)
+	}
+}
+
+// evaluateMapping replaces the state paths of a mapping with their values.
+// Paths that do not exist (yet) evaluate to null
+func evaluateMapping(value any, state *WorkflowState) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, field := range v {
+			evaluated, err := evaluateMapping(field, state)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = evaluated
+		}
+		return result, nil
+	case []any:
+		result := make([]any, 0, len(v))
+		for _, field := range v {
+			evaluated, err := evaluateMapping(field, state)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, evaluated)
+		}
+		return result, nil
+	case string:
+		path, isPath := strings.CutPrefix(v, statePathPrefix)
+		if !isPath {
+			return v, nil
+		}
+		return lookupStatePath(state, strings.Split(path, "."))
+	}
+
+	return value, nil
+}
+
+// lookupStatePath resolves input.<keys...> or steps.<Task>.<keys...>
+func lookupStatePath(state *WorkflowState, segments []string) (any, error) {
+	var raw json.RawMessage
+	switch {
+	case segments[0] == "input":
+		raw, segments = state.Input, segments[1:]
+	case segments[0] == "steps" && len(segments) > 1:
+		raw, segments = state.Steps[segments[1]], segments[2:]
+	default:
+		return nil, fmt.Errorf("invalid state path: %s", strings.Join(segments, "."))
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var current any
+	if err := json.Unmarshal(raw, &current); err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]any:
+			current = node[segment]
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, nil
+			}
+			current = node[index]
+		default:
+			return nil, nil
+		}
+	}
+
+	return current, nil
+}
@@ -0,0 +1,261 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+)
+
+const quickOrderYAML = `
+name: QuickOrder
+version: 2
+timeout: 10m
+steps:
+  - name: Validate
+    activity: Validate
+  - name: Charge
+    activity: Charge
+    compensation: Refund
+    timeout: 15s
+    retry:
+      max_attempts: 3
+      initial_interval: 5s
+      backoff_coefficient: 3
+  - name: Notify
+    activity: Notify
+    depends_on: [Validate]
+    input:
+      order_id: $.input.order_id
+      transaction_id: $.steps.Charge.transaction_id
+      channel: email
+`
+
+func catalogRegistry() *WorkflowRegistry {
+	reg := NewWorkflowRegistry()
+	for _, name := range []string{"Validate", "Charge", "Refund", "Notify"} {
+		reg.MustRegisterActivity(name, noop)
+	}
+	return reg
+}
+
+func TestParseDeclarativeWorkflow(t *testing.T) {
+	wf, err := ParseDeclarativeWorkflow([]byte(quickOrderYAML))
+	if err != nil {
+		t.Fatalf("ParseDeclarativeWorkflow: %v", err)
+	}
+
+	if wf.Name != "QuickOrder" || wf.Version != 2 || wf.Timeout != 10*time.Minute || len(wf.Steps) != 3 {
+		t.Fatalf("parsed %+v", wf)
+	}
+	charge := wf.Steps[1]
+	if charge.Timeout != 15*time.Second || charge.Compensation != "Refund" || charge.Retry == nil ||
+		charge.Retry.MaxAttempts != 3 || charge.Retry.InitialInterval != 5*time.Second || charge.Retry.BackoffCoefficient != 3 {
+		t.Errorf("Charge step = %+v, retry %+v", charge, charge.Retry)
+	}
+	if got, want := wf.Steps[2].DependsOn, []string{"Validate"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Notify.DependsOn = %v, want %v", got, want)
+	}
+}
+
+func TestParseDeclarativeWorkflowJSON(t *testing.T) {
+	wf, err := ParseDeclarativeWorkflow([]byte(`{"name": "J", "steps": [{"name": "A", "activity": "Validate", "timeout": "5s"}]}`))
+	if err != nil {
+		t.Fatalf("ParseDeclarativeWorkflow: %v", err)
+	}
+	if wf.Name != "J" || len(wf.Steps) != 1 || wf.Steps[0].Timeout != 5*time.Second {
+		t.Errorf("parsed %+v", wf)
+	}
+}
+
+func TestParseDeclarativeWorkflowUnknownField(t *testing.T) {
+	_, err := ParseDeclarativeWorkflow([]byte("name: X\nstepz: []\n"))
+	if err == nil {
+		t.Fatal("ParseDeclarativeWorkflow accepted an unknown field")
+	}
+}
+
+func TestRegisterDeclarative(t *testing.T) {
+	reg := catalogRegistry()
+	wf, _ := ParseDeclarativeWorkflow([]byte(quickOrderYAML))
+
+	if err := reg.RegisterDeclarative(wf); err != nil {
+		t.Fatalf("RegisterDeclarative: %v", err)
+	}
+
+	def, ok := reg.GetDefinitionVersion("QuickOrder", 2)
+	if !ok {
+		t.Fatal("QuickOrder v2 not registered")
+	}
+	if def.Timeout != 10*time.Minute {
+		t.Errorf("Timeout = %s", def.Timeout)
+	}
+	if got, want := def.Tasks["Charge"].DependsOn, []string{"Validate"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Charge.DependsOn = %v, want %v (sequential by default)", got, want)
+	}
+	if _, ok := def.CompensationFunc("Charge"); !ok {
+		t.Error("Charge has no compensation")
+	}
+	if got := def.Tasks["Charge"].RetryPolicy; got == nil || got.MaxAttempts != 3 {
+		t.Errorf("Charge.RetryPolicy = %+v", got)
+	}
+}
+
+func TestRegisterDeclarativeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"no name", "steps:\n  - {name: A, activity: Validate}\n", "name cannot be empty"},
+		{"no steps", "name: X\n", "has no steps"},
+		{"step without name", "name: X\nsteps:\n  - {activity: Validate}\n", "step without a name"},
+		{"unknown activity", "name: X\nsteps:\n  - {name: A, activity: Nope}\n", "unknown activity"},
+		{"unknown compensation", "name: X\nsteps:\n  - {name: A, activity: Validate, compensation: Nope}\n", "unknown compensation activity"},
+		{"mapping reads unknown step", "name: X\nsteps:\n  - {name: A, activity: Validate, input: {x: $.steps.Nope.y}}\n", "reads unknown step Nope"},
+		{"mapping with bad root", "name: X\nsteps:\n  - {name: A, activity: Validate, input: {x: $.env.y}}\n", "must start with $.input"},
+		{"unknown dependency", "name: X\nsteps:\n  - {name: A, activity: Validate, depends_on: [Nope]}\n", "unknown task: Nope"},
+		{"cycle", "name: X\nsteps:\n  - {name: A, activity: Validate, depends_on: [B]}\n  - {name: B, activity: Validate, depends_on: [A]}\n", "dependency cycle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := ParseDeclarativeWorkflow([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("ParseDeclarativeWorkflow: %v", err)
+			}
+			err = catalogRegistry().RegisterDeclarative(wf)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RegisterDeclarative error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"quick_order.yaml": quickOrderYAML,
+		"refund.json":      `{"name": "Refund", "steps": [{"name": "Refund", "activity": "Refund"}]}`,
+		"README.md":        "not a definition",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg := catalogRegistry()
+	names, err := reg.LoadDefinitions(dir)
+	if err != nil {
+		t.Fatalf("LoadDefinitions: %v", err)
+	}
+	if want := []string{"QuickOrder", "Refund"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("loaded %v, want %v", names, want)
+	}
+
+	if names, err := reg.LoadDefinitions(filepath.Join(dir, "missing")); err != nil || names != nil {
+		t.Errorf("LoadDefinitions(missing dir) = %v, %v; want nothing", names, err)
+	}
+}
+
+func TestLoadDefinitionsRegistersNothingOnError(t *testing.T) {
+	refund := `{"name": "Refund", "steps": [{"name": "Refund", "activity": "Refund"}]}`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"invalid file", map[string]string{
+			"a.yaml": quickOrderYAML,
+			"b.yaml": "name: Broken\nsteps:\n  - {name: A, activity: Nope}\n",
+			"c.json": refund,
+		}, "unknown activity"},
+		{"duplicate version across files", map[string]string{
+			"a.yaml": quickOrderYAML,
+			"b.json": refund,
+			"c.yaml": quickOrderYAML,
+		}, "already registered: QuickOrder v2"},
+		{"version registered in code", map[string]string{
+			"a.json": refund,
+			"b.yaml": strings.Replace(quickOrderYAML, "version: 2", "version: 1", 1),
+		}, "already registered: QuickOrder v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reg := catalogRegistry()
+			reg.NewWorkflow("QuickOrder").AddTask("Validate", noop).MustBuild()
+
+			names, err := reg.LoadDefinitions(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadDefinitions error = %v, want %q", err, tt.wantErr)
+			}
+			if names != nil {
+				t.Errorf("LoadDefinitions returned %v on error", names)
+			}
+			if _, ok := reg.GetDefinition("Refund"); ok {
+				t.Error("Refund registered although loading failed")
+			}
+			if def, _ := reg.GetDefinition("QuickOrder"); def == nil || def.Version != 1 {
+				t.Errorf("latest QuickOrder = %+v, want the v1 registered in code", def)
+			}
+		})
+	}
+}
+
+func TestInputMapping(t *testing.T) {
+	input := `{"order_id": "ORD-1", "items": [{"sku": "A"}]}`
+	state := NewWorkflowState(&input)
+	charge := `{"transaction_id": "TX-9"}`
+	state.SetStep("Charge", &charge)
+
+	mapping := map[string]any{
+		"order_id":       "$.input.order_id",
+		"first_sku":      "$.input.items.0.sku",
+		"transaction_id": "$.steps.Charge.transaction_id",
+		"missing":        "$.steps.Later.x",
+		"channel":        "email",
+		"nested":         []any{"$.input.order_id", 1},
+	}
+
+	var got map[string]any
+	fn := withInputMapping(func(ctx context.Context, task *model.Tasks) error {
+		return json.Unmarshal([]byte(*task.InputPayload), &got)
+	}, mapping)
+
+	stored := `{"original": true}`
+	task := &model.Tasks{InputPayload: &stored}
+	if err := fn(WithState(context.Background(), state), task); err != nil {
+		t.Fatalf("mapped task: %v", err)
+	}
+
+	want := map[string]any{
+		"order_id":       "ORD-1",
+		"first_sku":      "A",
+		"transaction_id": "TX-9",
+		"missing":        nil,
+		"channel":        "email",
+		"nested":         []any{"ORD-1", float64(1)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mapped input = %v, want %v", got, want)
+	}
+	if *task.InputPayload != stored {
+		t.Errorf("task input changed to %s", *task.InputPayload)
+	}
+}
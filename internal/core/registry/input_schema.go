@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// InputSchema checks the input payload of a workflow before an instance is
+// created. Validate returns one message per invalid field (nil if valid)
+type InputSchema interface {
+	Validate(input []byte) map[string]string
+}
+
+// schemaValidator reports fields by their JSON names (e.g. items[0].quantity)
+var schemaValidator = newSchemaValidator()
+
+func newSchemaValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	return v
+}
+
+// structSchema validates input by decoding it into T and checking the
+// `validate` tags of its fields
+type structSchema[T any] struct{}
+
+// InputOf declares T as the input schema of a workflow, typically the input
+// type of its first Typed task:
+//
+//	reg.NewWorkflow("OrderProcess").Input(registry.InputOf[OrderInput]())
+func InputOf[T any]() InputSchema {
+	return structSchema[T]{}
+}
+
+func (structSchema[T]) Validate(input []byte) map[string]string {
+	var value T
+	if err := json.Unmarshal(input, &value); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			field := fieldPath(typeErr.Field)
+			return map[string]string{field: field + " must be of type " + jsonType(typeErr.Type)}
+		}
+		return map[string]string{"input_payload": "input_payload is invalid: " + err.Error()}
+	}
+
+	err := validateWith(schemaValidator, value)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return map[string]string{"input_payload": err.Error()}
+	}
+
+	fields := make(map[string]string, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		// Drop the struct name: OrderInput.items[0].sku -> items[0].sku
+		_, field, found := strings.Cut(fieldErr.Namespace(), ".")
+		if !found {
+			field = fieldErr.Field()
+		}
+		fields[field] = fieldMessage(field, fieldErr)
+	}
+
+	return fields
+}
+
+// fieldMessage formats a failed `validate` tag for the API response
+func fieldMessage(field string, err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return field + " is required"
+	case "min":
+		return field + " must be at least " + err.Param()
+	case "max":
+		return field + " must be at most " + err.Param()
+	case "gt":
+		return field + " must be greater than " + err.Param()
+	case "gte":
+		return field + " must be greater than or equal to " + err.Param()
+	case "lt":
+		return field + " must be less than " + err.Param()
+	case "lte":
+		return field + " must be less than or equal to " + err.Param()
+	case "oneof":
+		return field + " must be one of: " + err.Param()
+	default:
+		return field + " is invalid"
+	}
+}
+
+// fieldPath writes a decoder field path (items.0.quantity) the way the
+// validator does (items[0].quantity)
+func fieldPath(path string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(part)
+	}
+
+	return b.String()
+}
+
+// jsonType names a Go type the way a JSON client sees it
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
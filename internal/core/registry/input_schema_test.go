@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testItem struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gt=0"`
+}
+
+type testOrderInput struct {
+	OrderID string     `json:"order_id" validate:"required"`
+	Amount  float64    `json:"amount" validate:"gt=0"`
+	Channel string     `json:"channel" validate:"omitempty,oneof=web pos"`
+	Items   []testItem `json:"items" validate:"dive"`
+}
+
+func TestInputOf(t *testing.T) {
+	schema := InputOf[testOrderInput]()
+
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name:  "valid",
+			input: `{"order_id": "ORD-1", "amount": 10, "items": [{"sku": "A", "quantity": 1}]}`,
+		},
+		{
+			name:  "missing fields",
+			input: `{}`,
+			want: map[string]string{
+				"order_id": "order_id is required",
+				"amount":   "amount must be greater than 0",
+			},
+		},
+		{
+			name:  "oneof",
+			input: `{"order_id": "ORD-1", "amount": 10, "channel": "fax"}`,
+			want:  map[string]string{"channel": "channel must be one of: web pos"},
+		},
+		{
+			name:  "nested items",
+			input: `{"order_id": "ORD-1", "amount": 10, "items": [{"sku": "A", "quantity": 1}, {"quantity": 0}]}`,
+			want: map[string]string{
+				"items[1].sku":      "items[1].sku is required",
+				"items[1].quantity": "items[1].quantity must be greater than 0",
+			},
+		},
+		{
+			name:  "wrong type",
+			input: `{"order_id": "ORD-1", "amount": "ten"}`,
+			want:  map[string]string{"amount": "amount must be of type number"},
+		},
+		{
+			name:  "wrong nested type",
+			input: `{"order_id": "ORD-1", "amount": 10, "items": [{"sku": "A", "quantity": "1"}]}`,
+			want:  map[string]string{"items[0].quantity": "items[0].quantity must be of type number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.Validate([]byte(tt.input))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputOfMalformedJSON(t *testing.T) {
+	got := InputOf[testOrderInput]().Validate([]byte(`{"order_id":`))
+	if _, ok := got["input_payload"]; !ok || len(got) != 1 {
+		t.Errorf("Validate = %v, want a single input_payload error", got)
+	}
+}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultMapParallelism is how many items of a map step run at once when
+// WithParallelism is not set
+const DefaultMapParallelism = 10
+
+// mapItemSeparator joins a map step name and an item index in task rows
+const mapItemSeparator = "#"
+
+// MapFailurePolicy decides what a permanently failed item does to its map step
+type MapFailurePolicy int
+
+const (
+	// MapFailFast fails the workflow as soon as one item fails
+	MapFailFast MapFailurePolicy = iota
+	// MapContinueOnError runs every item and reports the failed ones in the output
+	MapContinueOnError
+)
+
+// MapSpec describes a step that runs Func once for each element of the
+// array Field of its input payload
+type MapSpec struct {
+	Field         string
+	Func          TaskFunc
+	Parallelism   int
+	FailurePolicy MapFailurePolicy
+}
+
+// AddMap adds a step that splits the array field of its input into one task
+// per element, runs fn for each (the element is the item's input) and
+// outputs {"results": [...], "failed": [...]} with the item outputs in order
+func (b *WorkflowBuilder) AddMap(taskName, field string, fn TaskFunc, opts ...TaskOption) *WorkflowBuilder {
+	b.AddTask(taskName, nil, opts...)
+
+	task := b.tasks[taskName]
+	if task.Map == nil {
+		task.Map = &MapSpec{}
+	}
+	task.Map.Field = field
+	task.Map.Func = fn
+
+	return b
+}
+
+// WithParallelism limits how many items of a map step run at the same time
+func WithParallelism(n int) TaskOption {
+	return func(t *TaskDefinition) {
+		if t.Map == nil {
+			t.Map = &MapSpec{}
+		}
+		t.Map.Parallelism = n
+	}
+}
+
+// WithMapFailurePolicy sets how a map step handles items that fail permanently
+func WithMapFailurePolicy(policy MapFailurePolicy) TaskOption {
+	return func(t *TaskDefinition) {
+		if t.Map == nil {
+			t.Map = &MapSpec{}
+		}
+		t.Map.FailurePolicy = policy
+	}
+}
+
+// EffectiveParallelism returns the item limit, applying the default
+func (s *MapSpec) EffectiveParallelism() int {
+	if s.Parallelism <= 0 {
+		return DefaultMapParallelism
+	}
+
+	return s.Parallelism
+}
+
+// MapItemTaskName returns the task name of item index of a map step
+func MapItemTaskName(step string, index int) string {
+	return step + mapItemSeparator + strconv.Itoa(index)
+}
+
+// MapItemStep returns the map step and item index of an item task name
+func MapItemStep(taskName string) (string, int, bool) {
+	step, suffix, found := strings.Cut(taskName, mapItemSeparator)
+	if !found {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(suffix)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return step, index, true
+}
+
+// Step retrieves the definition a task row runs under: the task itself, or
+// its map step for item tasks
+func (d *WorkflowDefinition) Step(taskName string) (*TaskDefinition, bool) {
+	if step, _, isItem := MapItemStep(taskName); isItem {
+		taskDef, exists := d.Tasks[step]
+		if !exists || taskDef.Map == nil {
+			return nil, false
+		}
+		return taskDef, true
+	}
+
+	taskDef, exists := d.Tasks[taskName]
+
+	return taskDef, exists
+}
+
+// validateMaps checks map options and that task names cannot be mistaken
+// for map items
+func validateMaps(def *WorkflowDefinition) error {
+	for _, name := range def.TaskNames {
+		if strings.Contains(name, mapItemSeparator) {
+			return fmt.Errorf("task name cannot contain %s: %s", mapItemSeparator, name)
+		}
+
+		spec := def.Tasks[name].Map
+		if spec == nil {
+			continue
+		}
+		if spec.Func == nil || spec.Field == "" {
+			return fmt.Errorf("task %s has map options but is not a map step", name)
+		}
+		if spec.Parallelism < 0 {
+			return fmt.Errorf("map step %s has a negative parallelism", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package registry
+
+import "testing"
+
+func TestMapItemTaskName(t *testing.T) {
+	if got, want := MapItemTaskName("ChargeItems", 3), "ChargeItems#3"; got != want {
+		t.Errorf("MapItemTaskName = %q, want %q", got, want)
+	}
+}
+
+func TestMapItemStep(t *testing.T) {
+	tests := []struct {
+		taskName  string
+		wantStep  string
+		wantIndex int
+		wantItem  bool
+	}{
+		{"ChargeItems#0", "ChargeItems", 0, true},
+		{"ChargeItems#12", "ChargeItems", 12, true},
+		{"ChargeItems", "", 0, false},
+		{"ChargeItems#", "", 0, false},
+		{"ChargeItems#x", "", 0, false},
+		{"compensate:ChargeItems", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.taskName, func(t *testing.T) {
+			step, index, isItem := MapItemStep(tt.taskName)
+			if step != tt.wantStep || index != tt.wantIndex || isItem != tt.wantItem {
+				t.Errorf("MapItemStep(%q) = (%q, %d, %v), want (%q, %d, %v)",
+					tt.taskName, step, index, isItem, tt.wantStep, tt.wantIndex, tt.wantItem)
+			}
+		})
+	}
+}
+
+func TestMapItemRoundTrip(t *testing.T) {
+	for _, index := range []int{0, 1, 99} {
+		step, got, isItem := MapItemStep(MapItemTaskName("Ship", index))
+		if !isItem || step != "Ship" || got != index {
+			t.Errorf("round trip of index %d = (%q, %d, %v)", index, step, got, isItem)
+		}
+	}
+}
+
+func TestDefinitionStep(t *testing.T) {
+	reg := NewWorkflowRegistry()
+	if err := reg.NewWorkflow("Mapped").AddMap("Ship", "items", noop).Build(); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	def, _ := reg.GetDefinition("Mapped")
+
+	for _, name := range []string{"Ship", "Ship#4"} {
+		step, ok := def.Step(name)
+		if !ok || step.Name != "Ship" {
+			t.Errorf("Step(%q) = %v, %v; want the Ship step", name, step, ok)
+		}
+	}
+	if _, ok := def.Step("Other#1"); ok {
+		t.Error("Step(Other#1) found a step, want none")
+	}
+}
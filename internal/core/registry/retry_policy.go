@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how often and how fast a failed task is retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// InitialInterval is the delay before the first retry
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between retries (0 = no cap)
+	MaxInterval time.Duration
+	// BackoffCoefficient multiplies the delay after every retry
+	BackoffCoefficient float64
+	// Jitter randomises the delay by up to this fraction (0.2 = ±20%)
+	Jitter float64
+	// NonRetryableErrors fail the task immediately when errors.Is matches
+	NonRetryableErrors []error
+	// NonRetryableErrorTypes fail the task immediately when an error in the
+	// chain has one of these type names, as printed by %T
+	// (e.g. "*order.PaymentDeclinedError")
+	NonRetryableErrorTypes []string
+}
+
+// NonRetryableError marks an error that must not be retried under any policy
+type NonRetryableError struct {
+	Err error
+}
+
+func (e *NonRetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NonRetryableError) Unwrap() error {
+	return e.Err
+}
+
+// NonRetryable wraps err so the worker fails the task without retrying
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &NonRetryableError{Err: err}
+}
+
+// Merge returns p with zero fields taken from defaults
+func (p RetryPolicy) Merge(defaults RetryPolicy) RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = defaults.InitialInterval
+	}
+	if p.MaxInterval <= 0 {
+		p.MaxInterval = defaults.MaxInterval
+	}
+	if p.BackoffCoefficient <= 0 {
+		p.BackoffCoefficient = defaults.BackoffCoefficient
+	}
+	if p.Jitter <= 0 {
+		p.Jitter = defaults.Jitter
+	}
+	if p.NonRetryableErrors == nil {
+		p.NonRetryableErrors = defaults.NonRetryableErrors
+	}
+	if p.NonRetryableErrorTypes == nil {
+		p.NonRetryableErrorTypes = defaults.NonRetryableErrorTypes
+	}
+
+	return p
+}
+
+// Backoff returns the delay before the given retry (1 = first retry)
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	coefficient := p.BackoffCoefficient
+	if coefficient < 1 {
+		coefficient = 1
+	}
+
+	delay := float64(p.InitialInterval) * math.Pow(coefficient, float64(retry-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// ShouldRetry reports whether a task that has already run attempts times and
+// failed with err should be retried
+func (p RetryPolicy) ShouldRetry(attempts int, err error) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+
+	return p.IsRetryable(err)
+}
+
+// IsRetryable reports whether err is allowed to be retried by this policy
+func (p RetryPolicy) IsRetryable(err error) bool {
+	var nonRetryable *NonRetryableError
+	if errors.As(err, &nonRetryable) {
+		return false
+	}
+
+	for _, target := range p.NonRetryableErrors {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+
+	for _, typeName := range p.NonRetryableErrorTypes {
+		if hasErrorType(err, typeName) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasErrorType walks the error chain looking for an error whose %T is typeName
+func hasErrorType(err error, typeName string) bool {
+	for err != nil {
+		if fmt.Sprintf("%T", err) == typeName {
+			return true
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				if hasErrorType(inner, typeName) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+
+	return false
+}
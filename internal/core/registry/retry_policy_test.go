@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		retry  int
+		want   time.Duration
+	}{
+		{"first retry", RetryPolicy{InitialInterval: time.Second, BackoffCoefficient: 2}, 1, time.Second},
+		{"exponential", RetryPolicy{InitialInterval: time.Second, BackoffCoefficient: 2}, 4, 8 * time.Second},
+		{"coefficient below 1 is constant", RetryPolicy{InitialInterval: time.Second, BackoffCoefficient: 0.5}, 3, time.Second},
+		{"capped", RetryPolicy{InitialInterval: time.Second, BackoffCoefficient: 2, MaxInterval: 5 * time.Second}, 10, 5 * time.Second},
+		{"no cap", RetryPolicy{InitialInterval: time.Second, BackoffCoefficient: 3}, 3, 9 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.retry); got != tt.want {
+				t.Errorf("Backoff(%d) = %s, want %s", tt.retry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{InitialInterval: 10 * time.Second, BackoffCoefficient: 1, Jitter: 0.2}
+
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(1)
+		if got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("Backoff with 20%% jitter = %s, want within 8s..12s", got)
+		}
+	}
+}
+
+func TestRetryPolicyMerge(t *testing.T) {
+	defaults := RetryPolicy{MaxAttempts: 3, InitialInterval: time.Second, BackoffCoefficient: 2, MaxInterval: time.Minute}
+
+	got := RetryPolicy{MaxAttempts: 5}.Merge(defaults)
+	if got.MaxAttempts != 5 || got.InitialInterval != time.Second || got.BackoffCoefficient != 2 || got.MaxInterval != time.Minute {
+		t.Errorf("Merge = %+v", got)
+	}
+}
+
+type declinedError struct{}
+
+func (declinedError) Error() string { return "declined" }
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	errFatal := errors.New("fatal")
+	policy := RetryPolicy{
+		MaxAttempts:            3,
+		NonRetryableErrors:     []error{errFatal},
+		NonRetryableErrorTypes: []string{"registry.declinedError"},
+	}
+
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     bool
+	}{
+		{"retryable", 1, errors.New("boom"), true},
+		{"attempts used up", 3, errors.New("boom"), false},
+		{"NonRetryable wrapper", 1, NonRetryable(errors.New("boom")), false},
+		{"listed error", 1, fmt.Errorf("charge: %w", errFatal), false},
+		{"listed error type", 1, fmt.Errorf("charge: %w", declinedError{}), false},
+		{"listed error type in join", 1, errors.Join(errors.New("a"), declinedError{}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.ShouldRetry(tt.attempts, tt.err); got != tt.want {
+				t.Errorf("ShouldRetry(%d, %v) = %v, want %v", tt.attempts, tt.err, got, tt.want)
+			}
+		})
+	}
+}
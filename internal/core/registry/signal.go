@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// SignalSpec describes a step that waits for an external signal. With a
+// Timeout, the step gives up after that long and routes to Fallback
+type SignalSpec struct {
+	Name     string
+	Timeout  time.Duration
+	Fallback string
+}
+
+// AddSignalWait adds a step that waits until signalName is sent to the
+// instance (POST /workflows/:id/signals/:name). The signal payload becomes
+// the step's output
+func (b *WorkflowBuilder) AddSignalWait(taskName, signalName string, opts ...TaskOption) *WorkflowBuilder {
+	b.AddTask(taskName, nil, opts...)
+
+	task := b.tasks[taskName]
+	if task.Signal == nil {
+		task.Signal = &SignalSpec{}
+	}
+	task.Signal.Name = signalName
+
+	return b
+}
+
+// WithSignalTimeout makes a signal step stop waiting after timeout and
+// continue with fallback (or End) instead of its regular next steps
+func WithSignalTimeout(timeout time.Duration, fallback string) TaskOption {
+	return func(t *TaskDefinition) {
+		if t.Signal == nil {
+			t.Signal = &SignalSpec{}
+		}
+		t.Signal.Timeout = timeout
+		t.Signal.Fallback = fallback
+	}
+}
+
+// WaitsForSignal reports whether any step of the workflow waits for name
+func (d *WorkflowDefinition) WaitsForSignal(name string) bool {
+	return len(d.SignalTasks(name)) > 0
+}
+
+// SignalTasks returns the steps that wait for the signal name
+func (d *WorkflowDefinition) SignalTasks(name string) []string {
+	tasks := []string{}
+	for _, taskName := range d.TaskNames {
+		if signal := d.Tasks[taskName].Signal; signal != nil && signal.Name == name {
+			tasks = append(tasks, taskName)
+		}
+	}
+
+	return tasks
+}
+
+// validateSignals checks that signal options are only used on signal steps
+func validateSignals(def *WorkflowDefinition) error {
+	for _, name := range def.TaskNames {
+		signal := def.Tasks[name].Signal
+		if signal == nil {
+			continue
+		}
+		if signal.Name == "" {
+			return fmt.Errorf("task %s has a signal timeout but does not wait for a signal", name)
+		}
+		if signal.Timeout > 0 && signal.Fallback == "" {
+			return fmt.Errorf("signal step %s needs a fallback for its timeout", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// WorkflowState is the data an instance accumulated so far: its original
+// input and the output of every completed step, keyed by task name. It is
+// persisted in workflow_instances.state
+type WorkflowState struct {
+	Input json.RawMessage            `json:"input"`
+	Steps map[string]json.RawMessage `json:"steps"`
+}
+
+// stateKey is the context key of the workflow state
+type stateKey struct{}
+
+// NewWorkflowState returns the state of an instance that has just started
+func NewWorkflowState(input *string) *WorkflowState {
+	state := &WorkflowState{
+		Input: json.RawMessage("null"),
+		Steps: map[string]json.RawMessage{},
+	}
+	if input != nil && json.Valid([]byte(*input)) {
+		state.Input = json.RawMessage(*input)
+	}
+
+	return state
+}
+
+// ParseWorkflowState decodes a persisted state. Instances without one (from
+// before states were kept) start over from their input
+func ParseWorkflowState(state *string, input *string) (*WorkflowState, error) {
+	if state == nil {
+		return NewWorkflowState(input), nil
+	}
+
+	parsed := &WorkflowState{}
+	if err := json.Unmarshal([]byte(*state), parsed); err != nil {
+		return nil, fmt.Errorf("decode workflow state: %w", err)
+	}
+	if parsed.Steps == nil {
+		parsed.Steps = map[string]json.RawMessage{}
+	}
+
+	return parsed, nil
+}
+
+// SetStep records the output of a completed step
+func (s *WorkflowState) SetStep(taskName string, output *string) {
+	if output == nil || !json.Valid([]byte(*output)) {
+		s.Steps[taskName] = json.RawMessage("null")
+		return
+	}
+
+	s.Steps[taskName] = json.RawMessage(*output)
+}
+
+// Encode returns the state as persisted
+func (s *WorkflowState) Encode() (string, error) {
+	stateJSON, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	return string(stateJSON), nil
+}
+
+// DecodeInput decodes the workflow input into v
+func (s *WorkflowState) DecodeInput(v any) error {
+	return json.Unmarshal(s.Input, v)
+}
+
+// DecodeStep decodes the output of a completed step into v
+func (s *WorkflowState) DecodeStep(taskName string, v any) error {
+	output, exists := s.Steps[taskName]
+	if !exists {
+		return fmt.Errorf("step %s has not completed", taskName)
+	}
+
+	return json.Unmarshal(output, v)
+}
+
+// WithState returns a context carrying the workflow state for a TaskFunc
+func WithState(ctx context.Context, state *WorkflowState) context.Context {
+	return context.WithValue(ctx, stateKey{}, state)
+}
+
+// StateFromContext returns the state of the workflow a TaskFunc runs in
+func StateFromContext(ctx context.Context) (*WorkflowState, bool) {
+	state, ok := ctx.Value(stateKey{}).(*WorkflowState)
+	return state, ok && state != nil
+}
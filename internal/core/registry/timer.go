@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimerSpec describes when a timer step fires: a fixed Duration after the
+// step is reached, or the RFC3339 timestamp in the input payload's Field
+type TimerSpec struct {
+	Duration time.Duration
+	Field    string
+}
+
+// AddTimer adds a step that waits for d before the workflow continues
+func (b *WorkflowBuilder) AddTimer(taskName string, d time.Duration, opts ...TaskOption) *WorkflowBuilder {
+	b.AddTask(taskName, nil, opts...)
+	b.tasks[taskName].Timer = &TimerSpec{Duration: d}
+
+	return b
+}
+
+// AddTimerUntil adds a step that waits until the RFC3339 timestamp found in
+// field of its input payload (e.g. "remind_at")
+func (b *WorkflowBuilder) AddTimerUntil(taskName, field string, opts ...TaskOption) *WorkflowBuilder {
+	b.AddTask(taskName, nil, opts...)
+	b.tasks[taskName].Timer = &TimerSpec{Field: field}
+
+	return b
+}
+
+// FireAt returns the fire time of a timer reached at now with input
+func (s *TimerSpec) FireAt(input *string, now time.Time) (time.Time, error) {
+	if s.Field == "" {
+		return now.Add(s.Duration), nil
+	}
+
+	var payload map[string]any
+	if input != nil {
+		if err := json.Unmarshal([]byte(*input), &payload); err != nil {
+			return time.Time{}, fmt.Errorf("decode timer input: %w", err)
+		}
+	}
+
+	value, ok := payload[s.Field].(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("timer field %q is missing or not a string", s.Field)
+	}
+
+	fireAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timer field %q: %w", s.Field, err)
+	}
+
+	return fireAt, nil
+}
+
+// ScheduledAt returns when a task reached at now with input may start: the
+// fire time for timers, nil (right away) for other tasks. A timer whose fire
+// time cannot be read also starts right away and then fails
+func (t *TaskDefinition) ScheduledAt(input *string, now time.Time) *time.Time {
+	if t.Timer == nil {
+		return nil
+	}
+
+	fireAt, err := t.Timer.FireAt(input, now)
+	if err != nil {
+		return nil
+	}
+
+	return &fireAt
+}
+
+// validateTimers checks that every timer has a duration or a field
+func validateTimers(def *WorkflowDefinition) error {
+	for _, name := range def.TaskNames {
+		timer := def.Tasks[name].Timer
+		if timer != nil && timer.Field == "" && timer.Duration <= 0 {
+			return fmt.Errorf("timer %s needs a positive duration or a field", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+)
+
+// inputValidator checks the `validate` tags of typed task inputs
+var inputValidator = validator.New()
+
+// taskKey is the context key of the task row a typed function runs for
+type taskKey struct{}
+
+// Typed adapts a function on typed input and output to a TaskFunc. The input
+// payload is decoded into In and validated with its `validate` tags; Out is
+// encoded as the output payload. Undecodable or invalid input fails the task
+// without retry, since retrying cannot fix it
+func Typed[In, Out any](fn func(ctx context.Context, input In) (Out, error)) TaskFunc {
+	return func(ctx context.Context, task *model.Tasks) error {
+		var input In
+		if task.InputPayload != nil {
+			if err := json.Unmarshal([]byte(*task.InputPayload), &input); err != nil {
+				return NonRetryable(fmt.Errorf("decode input: %w", err))
+			}
+		}
+		if err := validateWith(inputValidator, input); err != nil {
+			return NonRetryable(fmt.Errorf("invalid input: %w", err))
+		}
+
+		output, err := fn(context.WithValue(ctx, taskKey{}, task), input)
+		if err != nil {
+			return err
+		}
+
+		outputJSON, err := json.Marshal(output)
+		if err != nil {
+			return NonRetryable(fmt.Errorf("encode output: %w", err))
+		}
+		outputStr := string(outputJSON)
+		task.OutputPayload = &outputStr
+
+		return nil
+	}
+}
+
+// TaskFromContext returns the task row a Typed function runs for
+func TaskFromContext(ctx context.Context) (*model.Tasks, bool) {
+	task, ok := ctx.Value(taskKey{}).(*model.Tasks)
+	return task, ok
+}
+
+// validateWith validates struct inputs with v; other types have no tags to check
+func validateWith(v *validator.Validate, input any) error {
+	value := reflect.ValueOf(input)
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return v.Struct(input)
+}
@@ -3,7 +3,10 @@ package registry
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
 )
@@ -11,48 +14,198 @@ import (
 // TaskFunc is the function signature for task execute
 type TaskFunc func(ctx context.Context, task *model.Tasks) error
 
-// WorkflowDefinition holds workflow name, tasks, and their functions
+// TaskDefinition holds a task function and its execution options
+type TaskDefinition struct {
+	Name        string
+	Func        TaskFunc
+	RetryPolicy *RetryPolicy
+	// Timeout is the start-to-close timeout of one attempt (0 = worker default)
+	Timeout time.Duration
+	// DependsOn lists the tasks that must complete before this one starts
+	DependsOn []string
+	// Branches choose the next task from this task's output (see Branch)
+	Branches []BranchCase
+	// Compensation undoes the task if the workflow fails later (optional)
+	Compensation TaskFunc
+	// ChildWorkflow is the workflow started by a child workflow step (see AddChildWorkflow)
+	ChildWorkflow string
+	// Timer makes the task a durable timer step (see AddTimer)
+	Timer *TimerSpec
+	// Signal makes the task wait for an external signal (see AddSignalWait)
+	Signal *SignalSpec
+	// Map runs a function for each element of an input array (see AddMap)
+	Map *MapSpec
+}
+
+// TaskOption configures a task added with AddTask
+type TaskOption func(*TaskDefinition)
+
+// WithRetryPolicy overrides the worker's default retry policy for a task.
+// Zero fields fall back to the worker defaults
+func WithRetryPolicy(policy RetryPolicy) TaskOption {
+	return func(t *TaskDefinition) {
+		t.RetryPolicy = &policy
+	}
+}
+
+// WithTimeout sets the start-to-close timeout of each attempt of a task
+func WithTimeout(timeout time.Duration) TaskOption {
+	return func(t *TaskDefinition) {
+		t.Timeout = timeout
+	}
+}
+
+// WorkflowDefinition holds workflow name, tasks, and their definitions
 type WorkflowDefinition struct {
 	Name      string
 	TaskNames []string
-	TaskFuncs map[string]TaskFunc
+	Tasks     map[string]*TaskDefinition
+	// Timeout is the overall deadline of an instance, measured from its start (0 = none)
+	Timeout time.Duration
+	// Version of the definition; instances keep running on the version they started with (default 1)
+	Version int
+	// Input validates the payload of new instances (nil = any payload)
+	Input InputSchema
 }
 
 // WorkflowRegistry manages workflow definitions
 type WorkflowRegistry struct {
-	mu          sync.RWMutex
+	mu sync.RWMutex
+	// definitions holds the latest version of each workflow, used to start new instances
 	definitions map[string]*WorkflowDefinition
+	// versions holds every registered version, for instances still running on older ones
+	versions map[string]map[int]*WorkflowDefinition
+	// activities are the task functions declarative workflows can reference
+	activities map[string]TaskFunc
 }
 
 // NameWorkflowRegistry creates a new registry
 func NewWorkflowRegistry() *WorkflowRegistry {
 	return &WorkflowRegistry{
 		definitions: make(map[string]*WorkflowDefinition),
+		versions:    make(map[string]map[int]*WorkflowDefinition),
+		activities:  make(map[string]TaskFunc),
 	}
 }
 
 // Register adds a complete workflow definition
 func (r *WorkflowRegistry) Register(def *WorkflowDefinition) error {
+	return r.register(def)
+}
+
+// register adds defs together: if one of them is invalid, a duplicate or
+// closes a child workflow cycle, none of them is registered
+func (r *WorkflowRegistry) register(defs ...*WorkflowDefinition) error {
+	for _, def := range defs {
+		if err := validateDefinition(def); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := []*WorkflowDefinition{}
+	for _, def := range defs {
+		if err := r.add(def); err != nil {
+			r.remove(added)
+			return err
+		}
+		added = append(added, def)
+	}
+
+	return nil
+}
+
+// validateDefinition runs the checks that need no other workflow
+func validateDefinition(def *WorkflowDefinition) error {
 	if def.Name == "" {
 		return errors.New("workflow name cannot be empty")
 	}
 	if len(def.TaskNames) == 0 {
 		return errors.New("workflow must have at least one task")
 	}
+	if def.Version == 0 {
+		def.Version = 1
+	}
+	if def.Version < 0 {
+		return fmt.Errorf("workflow %s has an invalid version: %d", def.Name, def.Version)
+	}
+	for _, name := range def.TaskNames {
+		task, exists := def.Tasks[name]
+		if !exists || (task.Func == nil && task.ChildWorkflow == "" && task.Timer == nil && task.Signal == nil && task.Map == nil) {
+			return errors.New("task function not defined: " + name)
+		}
+		if task.ChildWorkflow == def.Name {
+			return errors.New("workflow cannot start itself as a child: " + name)
+		}
+		if strings.HasPrefix(name, compensationPrefix) {
+			return errors.New("task name cannot start with " + compensationPrefix + ": " + name)
+		}
+	}
+	if err := validateTimers(def); err != nil {
+		return err
+	}
+	if err := validateSignals(def); err != nil {
+		return err
+	}
+	if err := validateMaps(def); err != nil {
+		return err
+	}
+	if err := validateRoutes(def); err != nil {
+		return err
+	}
+	if err := validateDAG(def); err != nil {
+		return err
+	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	return nil
+}
+
+// add registers one checked definition. r.mu must be held
+func (r *WorkflowRegistry) add(def *WorkflowDefinition) error {
+	if _, exists := r.versions[def.Name][def.Version]; exists {
+		return fmt.Errorf("workflow already registered: %s v%d", def.Name, def.Version)
+	}
+	if err := r.validateChildCycles(def); err != nil {
+		return err
+	}
 
-	if _, exists := r.definitions[def.Name]; exists {
-		return errors.New("workflow already registered: " + def.Name)
+	if r.versions[def.Name] == nil {
+		r.versions[def.Name] = make(map[int]*WorkflowDefinition)
 	}
+	r.versions[def.Name][def.Version] = def
 
-	r.definitions[def.Name] = def
+	if latest, exists := r.definitions[def.Name]; !exists || def.Version > latest.Version {
+		r.definitions[def.Name] = def
+	}
 
 	return nil
 }
 
-// GetDefinition retrieves workflow definition
+// remove undoes add for defs, restoring the previous latest versions. r.mu must be held
+func (r *WorkflowRegistry) remove(defs []*WorkflowDefinition) {
+	for _, def := range defs {
+		delete(r.versions[def.Name], def.Version)
+		if len(r.versions[def.Name]) == 0 {
+			delete(r.versions, def.Name)
+			delete(r.definitions, def.Name)
+			continue
+		}
+
+		if r.definitions[def.Name] == def {
+			var latest *WorkflowDefinition
+			for _, version := range r.versions[def.Name] {
+				if latest == nil || version.Version > latest.Version {
+					latest = version
+				}
+			}
+			r.definitions[def.Name] = latest
+		}
+	}
+}
+
+// GetDefinition retrieves the latest version of a workflow definition
 func (r *WorkflowRegistry) GetDefinition(name string) (*WorkflowDefinition, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -61,6 +214,15 @@ func (r *WorkflowRegistry) GetDefinition(name string) (*WorkflowDefinition, bool
 	return def, exists
 }
 
+// GetDefinitionVersion retrieves one version of a workflow definition
+func (r *WorkflowRegistry) GetDefinitionVersion(name string, version int) (*WorkflowDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, exists := r.versions[name][version]
+	return def, exists
+}
+
 // GetTaskFunc retrieves a specific task function
 func (r *WorkflowRegistry) GetTaskFunc(workflowName, taskName string) (TaskFunc, bool) {
 	r.mu.RLock()
@@ -71,9 +233,41 @@ func (r *WorkflowRegistry) GetTaskFunc(workflowName, taskName string) (TaskFunc,
 		return nil, false
 	}
 
-	fn, exists := def.TaskFuncs[taskName]
+	return def.TaskFunc(taskName)
+}
+
+// TaskFunc retrieves the function a task row runs
+func (d *WorkflowDefinition) TaskFunc(taskName string) (TaskFunc, bool) {
+	// Items of a map step run the step's function
+	if step, _, isItem := MapItemStep(taskName); isItem {
+		task, exists := d.Tasks[step]
+		if !exists || task.Map == nil {
+			return nil, false
+		}
+		return task.Map.Func, true
+	}
+
+	task, exists := d.Tasks[taskName]
+	if !exists {
+		return nil, false
+	}
 
-	return fn, exists
+	return task.Func, true
+}
+
+// GetTask retrieves a specific task definition
+func (r *WorkflowRegistry) GetTask(workflowName, taskName string) (*TaskDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, exists := r.definitions[workflowName]
+	if !exists {
+		return nil, false
+	}
+
+	task, exists := def.Tasks[taskName]
+
+	return task, exists
 }
 
 // ListWorkflows returns all registered workflow names
@@ -98,7 +292,11 @@ type WorkflowBuilder struct {
 	registry  *WorkflowRegistry
 	name      string
 	taskNames []string
-	taskFuncs map[string]TaskFunc
+	tasks     map[string]*TaskDefinition
+	timeout   time.Duration
+	version   int
+	input     InputSchema
+	err       error
 }
 
 // NewWorkflow creates a new workflow builder
@@ -107,29 +305,80 @@ func (r *WorkflowRegistry) NewWorkflow(name string) *WorkflowBuilder {
 		registry:  r,
 		name:      name,
 		taskNames: []string{},
-		taskFuncs: make(map[string]TaskFunc),
+		tasks:     make(map[string]*TaskDefinition),
 	}
 }
 
-// AddTask adds a task with its execution function
-func (b *WorkflowBuilder) AddTask(taskName string, fn TaskFunc) *WorkflowBuilder {
+// AddTask adds a task with its execution function and options
+func (b *WorkflowBuilder) AddTask(taskName string, fn TaskFunc, opts ...TaskOption) *WorkflowBuilder {
+	task := &TaskDefinition{
+		Name: taskName,
+		Func: fn,
+	}
+	for _, opt := range opts {
+		opt(task)
+	}
+
+	// Sequential by default: run after the previously added task
+	if task.DependsOn == nil && len(b.taskNames) > 0 {
+		task.DependsOn = []string{b.taskNames[len(b.taskNames)-1]}
+	}
+
 	b.taskNames = append(b.taskNames, taskName)
-	b.taskFuncs[taskName] = fn
+	b.tasks[taskName] = task
+
+	return b
+}
+
+// Timeout sets the overall deadline of each workflow instance
+func (b *WorkflowBuilder) Timeout(timeout time.Duration) *WorkflowBuilder {
+	b.timeout = timeout
+
+	return b
+}
+
+// Version sets the definition version. Register a new version instead of
+// changing the steps of a workflow with instances still running
+func (b *WorkflowBuilder) Version(version int) *WorkflowBuilder {
+	b.version = version
+
+	return b
+}
+
+// Input sets the schema new instances' input payload must match. Starting a
+// workflow with a non-conforming payload fails before anything is written
+func (b *WorkflowBuilder) Input(schema InputSchema) *WorkflowBuilder {
+	b.input = schema
 
 	return b
 }
 
 // Builder registers the workflow
 func (b *WorkflowBuilder) Build() error {
-	def := &WorkflowDefinition{
-		Name:      b.name,
-		TaskNames: b.taskNames,
-		TaskFuncs: b.taskFuncs,
+	def, err := b.definition()
+	if err != nil {
+		return err
 	}
 
 	return b.registry.Register(def)
 }
 
+// definition returns the built workflow without registering it
+func (b *WorkflowBuilder) definition() (*WorkflowDefinition, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &WorkflowDefinition{
+		Name:      b.name,
+		TaskNames: b.taskNames,
+		Tasks:     b.tasks,
+		Timeout:   b.timeout,
+		Version:   b.version,
+		Input:     b.input,
+	}, nil
+}
+
 // MustBuild registers the workflow and panics on error
 func (b *WorkflowBuilder) MustBuild() {
 	if err := b.Build(); err != nil {
@@ -0,0 +1,249 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/config"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+	"github.com/parinyadagon/go-workflow/pkg/cron"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// errRunFailed marks a failure of the schedule itself (its cron expression,
+// or starting its workflow) rather than of the database
+var errRunFailed = errors.New("scheduled run failed")
+
+// Scheduler starts workflow instances from cron schedules. Every replica can
+// run one: each run is claimed under a row lock in the same transaction that
+// creates the instance and advances next_run_at, so it fires exactly once
+type Scheduler struct {
+	repo         port.WorkflowRepository
+	schedules    port.ScheduleRepository
+	svc          port.WorkflowService
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func NewScheduler(repo port.WorkflowRepository, schedules port.ScheduleRepository, svc port.WorkflowService, cfg *config.SchedulerConfig) *Scheduler {
+	return &Scheduler{
+		repo:         repo,
+		schedules:    schedules,
+		svc:          svc,
+		pollInterval: cfg.PollInterval,
+		batchSize:    cfg.BatchSize,
+	}
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	logger.Info().Dur("poll_interval", s.pollInterval).Msg("Scheduler started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Msg("Scheduler stopped")
+			return
+		case <-ticker.C:
+			s.fireDueSchedules(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) fireDueSchedules(ctx context.Context) {
+	now := time.Now()
+
+	ids, err := s.schedules.GetDueScheduleIDs(ctx, now, s.batchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error fetching due schedules")
+		return
+	}
+
+	for _, id := range ids {
+		// One transaction per schedule so a broken one does not hold back the rest
+		err := s.repo.WithTx(ctx, func(ctx context.Context) error {
+			return s.fire(ctx, id, now)
+		})
+		if err == nil {
+			continue
+		}
+
+		logger.Error().Err(err).Str("schedule_id", id).Msg("Failed to fire schedule")
+		if errors.Is(err, errRunFailed) {
+			s.recordFailure(ctx, id, now, err)
+		}
+	}
+}
+
+// recordFailure moves a schedule whose run failed on to its next run and keeps
+// the error in last_error, so it is not retried on every poll. A schedule
+// with no next run (e.g. its time zone is gone) is paused
+func (s *Scheduler) recordFailure(ctx context.Context, id string, now time.Time, runErr error) {
+	err := s.repo.WithTx(ctx, func(ctx context.Context) error {
+		schedule, err := s.schedules.LockDueSchedule(ctx, id, now)
+		if err != nil || schedule == nil {
+			return err
+		}
+
+		next, err := cron.NextRun(schedule.CronExpression, schedule.TimeZone, now)
+		if err != nil {
+			schedule.Paused = true
+		} else {
+			schedule.NextRunAt = next
+		}
+		message := runErr.Error()
+		schedule.LastError = &message
+		schedule.Buffered = false
+
+		logger.Warn().
+			Str("schedule_id", schedule.ID).
+			Str("schedule_name", schedule.Name).
+			Time("next_run_at", schedule.NextRunAt).
+			Bool("paused", schedule.Paused).
+			Msg("Scheduled run skipped after error")
+
+		return s.schedules.RecordScheduleFailure(ctx, schedule)
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("schedule_id", id).Msg("Failed to record schedule failure")
+	}
+}
+
+// fire starts the due run of one schedule, applying its overlap policy when
+// the instance from the previous run is still active
+func (s *Scheduler) fire(ctx context.Context, id string, now time.Time) error {
+	schedule, err := s.schedules.LockDueSchedule(ctx, id, now)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		// Fired by another replica, paused or deleted meanwhile
+		return nil
+	}
+
+	due := !schedule.NextRunAt.After(now)
+	var scheduledFor *time.Time
+	if due {
+		runAt := schedule.NextRunAt
+		scheduledFor = &runAt
+
+		// Next run from now: runs missed while no replica was up are not backfilled
+		next, err := cron.NextRun(schedule.CronExpression, schedule.TimeZone, now)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errRunFailed, err)
+		}
+		schedule.NextRunAt = next
+	}
+
+	previousActive, err := s.previousRunActive(ctx, schedule)
+	if err != nil {
+		return err
+	}
+
+	policy := model.WorkflowSchedulesOverlapPolicy_Skip
+	if schedule.OverlapPolicy != nil {
+		policy = *schedule.OverlapPolicy
+	}
+
+	start := due || schedule.Buffered
+	buffered := schedule.Buffered
+	if previousActive && policy != model.WorkflowSchedulesOverlapPolicy_Allow {
+		if policy == model.WorkflowSchedulesOverlapPolicy_BufferOne && due {
+			// Keep at most one run waiting for the previous instance
+			schedule.Buffered = true
+		}
+		if due {
+			logger.Info().
+				Str("schedule_id", schedule.ID).
+				Str("schedule_name", schedule.Name).
+				Str("last_workflow_id", *schedule.LastWorkflowID).
+				Str("overlap_policy", policy.String()).
+				Bool("buffered", schedule.Buffered).
+				Msg("Previous scheduled run still active")
+		}
+		return s.schedules.UpdateScheduleRun(ctx, schedule)
+	}
+
+	if start {
+		if err := s.startRun(ctx, schedule, scheduledFor, buffered, now); err != nil {
+			return err
+		}
+		schedule.LastError = nil
+	}
+	schedule.Buffered = false
+
+	return s.schedules.UpdateScheduleRun(ctx, schedule)
+}
+
+// previousRunActive reports whether the last instance started by the schedule
+// has not finished yet
+func (s *Scheduler) previousRunActive(ctx context.Context, schedule *model.WorkflowSchedules) (bool, error) {
+	if schedule.LastWorkflowID == nil {
+		return false, nil
+	}
+
+	wf, err := s.repo.GetWorkflowByID(ctx, *schedule.LastWorkflowID)
+	if err != nil {
+		return false, err
+	}
+
+	return wf.Status == nil ||
+		*wf.Status == model.WorkflowInstancesStatus_Scheduled ||
+		*wf.Status == model.WorkflowInstancesStatus_Pending ||
+		*wf.Status == model.WorkflowInstancesStatus_Running ||
+		*wf.Status == model.WorkflowInstancesStatus_Compensating, nil
+}
+
+// startRun creates the workflow instance through the workflow service; it
+// joins the schedule's transaction
+func (s *Scheduler) startRun(ctx context.Context, schedule *model.WorkflowSchedules, scheduledFor *time.Time, buffered bool, now time.Time) error {
+	var input map[string]any
+	if schedule.InputPayload != nil {
+		if err := json.Unmarshal([]byte(*schedule.InputPayload), &input); err != nil {
+			return fmt.Errorf("%w: %v", errRunFailed, err)
+		}
+	}
+
+	wf, err := s.svc.StartNewWorkflow(ctx, &port.CreateWorkflowRequest{
+		WorkflowName: schedule.WorkflowName,
+		InputPayload: input,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRunFailed, err)
+	}
+
+	schedule.LastRunAt = &now
+	schedule.LastWorkflowID = &wf.ID
+
+	logger.Info().
+		Str("schedule_id", schedule.ID).
+		Str("schedule_name", schedule.Name).
+		Str("workflow_id", wf.ID).
+		Str("workflow_name", wf.WorkflowName).
+		Bool("buffered", buffered).
+		Msg("Scheduled workflow started")
+
+	details := map[string]any{
+		"schedule_id":   schedule.ID,
+		"schedule_name": schedule.Name,
+		"buffered":      buffered,
+	}
+	if scheduledFor != nil {
+		details["scheduled_for"] = scheduledFor.Format(time.RFC3339)
+	}
+	detailsJSON, _ := json.Marshal(details)
+	detailsStr := string(detailsJSON)
+	eventType := "SCHEDULE_TRIGGERED"
+
+	return s.repo.CreateActivityLog(ctx, &model.ActivityLogs{
+		WorkflowInstanceID: wf.ID,
+		EventType:          &eventType,
+		Details:            &detailsStr,
+	})
+}
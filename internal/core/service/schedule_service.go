@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/cron"
+)
+
+type scheduleService struct {
+	repo     port.ScheduleRepository
+	registry *registry.WorkflowRegistry
+}
+
+func NewScheduleService(repo port.ScheduleRepository, reg *registry.WorkflowRegistry) port.ScheduleService {
+	return &scheduleService{
+		repo:     repo,
+		registry: reg,
+	}
+}
+
+// applyRequest validates req and copies it onto schedule, recomputing the next run
+func (s *scheduleService) applyRequest(schedule *model.WorkflowSchedules, req *port.ScheduleRequest) error {
+	def, exists := s.registry.GetDefinition(req.WorkflowName)
+	if !exists {
+		return fmt.Errorf("%w: unknown workflow: %s", port.ErrInvalidSchedule, req.WorkflowName)
+	}
+
+	timeZone := req.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	policy := model.WorkflowSchedulesOverlapPolicy_Skip
+	if req.OverlapPolicy != "" {
+		policy = model.WorkflowSchedulesOverlapPolicy(req.OverlapPolicy)
+	}
+
+	nextRunAt, err := cron.NextRun(req.CronExpression, timeZone, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrInvalidSchedule, err)
+	}
+
+	// Every run starts with this payload, so check it once here
+	inputJSON, _ := json.Marshal(req.InputPayload)
+	if def.Input != nil {
+		if fields := def.Input.Validate(inputJSON); len(fields) > 0 {
+			return &port.InputValidationError{Fields: fields}
+		}
+	}
+
+	var inputStr *string
+	if req.InputPayload != nil {
+		str := string(inputJSON)
+		inputStr = &str
+	}
+
+	schedule.Name = req.Name
+	schedule.WorkflowName = req.WorkflowName
+	schedule.CronExpression = req.CronExpression
+	schedule.TimeZone = timeZone
+	schedule.InputPayload = inputStr
+	schedule.OverlapPolicy = &policy
+	schedule.Paused = req.Paused
+	schedule.Buffered = false
+	schedule.NextRunAt = nextRunAt
+
+	return nil
+}
+
+func (s *scheduleService) CreateSchedule(ctx context.Context, req *port.ScheduleRequest) (*model.WorkflowSchedules, error) {
+	schedule := &model.WorkflowSchedules{ID: uuid.New().String()}
+	if err := s.applyRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetScheduleByID(ctx, schedule.ID)
+}
+
+func (s *scheduleService) ListSchedules(ctx context.Context, limit int, offset int) ([]model.WorkflowSchedules, error) {
+	return s.repo.ListSchedules(ctx, limit, offset)
+}
+
+func (s *scheduleService) GetSchedule(ctx context.Context, id string) (*model.WorkflowSchedules, error) {
+	return s.repo.GetScheduleByID(ctx, id)
+}
+
+// UpdateSchedule replaces a schedule's definition. A buffered run is dropped
+// and the next run is computed from now
+func (s *scheduleService) UpdateSchedule(ctx context.Context, id string, req *port.ScheduleRequest) (*model.WorkflowSchedules, error) {
+	schedule, err := s.repo.GetScheduleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetScheduleByID(ctx, id)
+}
+
+func (s *scheduleService) DeleteSchedule(ctx context.Context, id string) error {
+	deleted, err := s.repo.DeleteSchedule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return port.ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+func (s *scheduleService) PauseSchedule(ctx context.Context, id string) (*model.WorkflowSchedules, error) {
+	return s.setPaused(ctx, id, true)
+}
+
+// ResumeSchedule restarts a paused schedule from now; runs missed while it
+// was paused are not made up
+func (s *scheduleService) ResumeSchedule(ctx context.Context, id string) (*model.WorkflowSchedules, error) {
+	return s.setPaused(ctx, id, false)
+}
+
+func (s *scheduleService) setPaused(ctx context.Context, id string, paused bool) (*model.WorkflowSchedules, error) {
+	schedule, err := s.repo.GetScheduleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.Paused = paused
+	schedule.Buffered = false
+	if !paused {
+		nextRunAt, err := cron.NextRun(schedule.CronExpression, schedule.TimeZone, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		schedule.NextRunAt = nextRunAt
+	}
+
+	if err := s.repo.UpdateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetScheduleByID(ctx, id)
+}
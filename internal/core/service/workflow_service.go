@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
@@ -27,46 +31,178 @@ func (s *workflowService) ListAvailableWorkflows(ctx context.Context) []string {
 	return s.registry.ListWorkflows()
 }
 
+// startTime resolves the requested start of a workflow; nil means right away
+func startTime(req *port.CreateWorkflowRequest, now time.Time) (*time.Time, error) {
+	if req.StartAt != nil && req.Delay != "" {
+		return nil, fmt.Errorf("%w: set either start_at or delay", port.ErrInvalidStartTime)
+	}
+
+	startAt := req.StartAt
+	if req.Delay != "" {
+		delay, err := time.ParseDuration(req.Delay)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("%w: delay must be a positive duration like \"30m\" or \"2h\"", port.ErrInvalidStartTime)
+		}
+		at := now.Add(delay)
+		startAt = &at
+	}
+
+	// A start time that already passed starts the workflow right away
+	if startAt == nil || !startAt.After(now) {
+		return nil, nil
+	}
+
+	return startAt, nil
+}
+
+// requestHash fingerprints the parts of a start request that decide which
+// instance it creates, to tell a retry from a reused idempotency key
+func requestHash(req *port.CreateWorkflowRequest) string {
+	fields := map[string]any{
+		"workflow_name": req.WorkflowName,
+		"input_payload": req.InputPayload,
+		"delay":         req.Delay,
+	}
+	if req.StartAt != nil {
+		fields["start_at"] = req.StartAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	// json.Marshal sorts map keys, so equal requests hash the same
+	fieldsJSON, _ := json.Marshal(fields)
+	sum := sha256.Sum256(fieldsJSON)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotentReplay returns the instance an earlier request with the same key
+// created (nil if none), or ErrIdempotencyKeyReused if that request differed
+func (s *workflowService) idempotentReplay(ctx context.Context, key string, hash string) (*model.WorkflowInstances, error) {
+	existing, err := s.repo.GetWorkflowByIdempotencyKey(ctx, key)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if existing.RequestHash == nil || *existing.RequestHash != hash {
+		return nil, port.ErrIdempotencyKeyReused
+	}
+
+	return existing, nil
+}
+
 func (s *workflowService) StartNewWorkflow(ctx context.Context, req *port.CreateWorkflowRequest) (*model.WorkflowInstances, error) {
 	newID := uuid.New().String()
 	status := model.WorkflowInstancesStatus_Pending
 
-	inputJSON, _ := json.Marshal(req.InputPayload)
-	inputStr := string(inputJSON)
+	// Client retries with the same key get the original instance back
+	var idempotencyKey, hash *string
+	if req.IdempotencyKey != "" {
+		key, sum := req.IdempotencyKey, requestHash(req)
+		existing, err := s.idempotentReplay(ctx, key, sum)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+		idempotencyKey, hash = &key, &sum
+	}
 
-	wf := &model.WorkflowInstances{
-		ID:           newID,
-		WorkflowName: req.WorkflowName,
-		Status:       &status,
-		CurrentInput: &inputStr,
+	now := time.Now()
+	startAt, err := startTime(req, now)
+	if err != nil {
+		return nil, err
+	}
+	if startAt != nil {
+		// Worker เริ่ม task แรกเมื่อถึงเวลา start_at
+		status = model.WorkflowInstancesStatus_Scheduled
+		now = *startAt
 	}
 
+	inputJSON, _ := json.Marshal(req.InputPayload)
+	inputStr := string(inputJSON)
+
 	def, exists := s.registry.GetDefinition(req.WorkflowName)
 	if !exists || len(def.TaskNames) == 0 {
 		return nil, fmt.Errorf("unknown workflow: %s", req.WorkflowName)
 	}
+	// Reject bad input up front instead of failing the first task with retries
+	if def.Input != nil {
+		if fields := def.Input.Validate(inputJSON); len(fields) > 0 {
+			return nil, &port.InputValidationError{Fields: fields}
+		}
+	}
 
-	firstTaskName := def.TaskNames[0]
-	taskStatus := model.TasksStatus_Pending
+	// State สะสม input + output ของทุก step ตลอดอายุ workflow
+	stateStr, err := registry.NewWorkflowState(&inputStr).Encode()
+	if err != nil {
+		return nil, err
+	}
 
-	firstTask := &model.Tasks{
-		WorkflowInstanceID: newID,
-		TaskName:           firstTaskName,
-		Status:             &taskStatus,
-		InputPayload:       &inputStr,
+	wf := &model.WorkflowInstances{
+		ID:             newID,
+		WorkflowName:   req.WorkflowName,
+		Status:         &status,
+		CurrentInput:   &inputStr,
+		State:          &stateStr,
+		ParentID:       req.ParentID,
+		ParentTaskID:   req.ParentTaskID,
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    hash,
 	}
 
-	if err := s.repo.CreateWorkflow(ctx, wf); err != nil {
-		return nil, err
+	// New instances start on the latest version and stay on it
+	wf.WorkflowVersion = int32(def.Version)
+
+	// The deadline counts from the start time, not from the request
+	if def.Timeout > 0 {
+		deadline := now.Add(def.Timeout)
+		wf.DeadlineAt = &deadline
 	}
 
-	if err := s.repo.CreateTask(ctx, firstTask); err != nil {
+	// Task ที่ไม่มี dependency เริ่มพร้อมกันทั้งหมด
+	taskStatus := model.TasksStatus_Pending
+	rootTasks := def.RootTasks()
+
+	// Instance และ root task ต้องเกิดพร้อมกัน ไม่งั้น workflow จะค้างไม่มีใครทำต่อ
+	err = s.repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateWorkflow(ctx, wf); err != nil {
+			return err
+		}
+		for _, taskName := range rootTasks {
+			if err := s.repo.CreateTask(ctx, &model.Tasks{
+				WorkflowInstanceID: newID,
+				TaskName:           taskName,
+				Status:             &taskStatus,
+				InputPayload:       &inputStr,
+				ScheduledAt:        rootScheduledAt(def.Tasks[taskName], &inputStr, now, startAt),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, port.ErrIdempotencyKeyExists) && idempotencyKey != nil {
+		// A concurrent request with the same key committed first
+		existing, err := s.idempotentReplay(ctx, *idempotencyKey, *hash)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+		return nil, port.ErrIdempotencyKeyExists
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	return wf, nil
 }
 
+// rootScheduledAt returns when a root task may be claimed: the later of its
+// timer fire time and the delayed start time (nil for right away)
+func rootScheduledAt(taskDef *registry.TaskDefinition, input *string, now time.Time, startAt *time.Time) *time.Time {
+	scheduledAt := taskDef.ScheduledAt(input, now)
+	if scheduledAt == nil || (startAt != nil && startAt.After(*scheduledAt)) {
+		return startAt
+	}
+
+	return scheduledAt
+}
+
 func (s *workflowService) ListWorkflows(ctx context.Context, limit int, offset int) ([]model.WorkflowInstances, error) {
 	return s.repo.ListWorkflows(ctx, limit, offset)
 }
@@ -85,3 +221,151 @@ func (s *workflowService) GetTasksByWorkflowID(ctx context.Context, wfID string)
 func (s *workflowService) GetActivityLogsByWorkflowID(ctx context.Context, wfID string) ([]model.ActivityLogs, error) {
 	return s.repo.GetActivityLogsByWorkflowID(ctx, wfID)
 }
+
+func (s *workflowService) GetChildWorkflows(ctx context.Context, parentID string) ([]model.WorkflowInstances, error) {
+	return s.repo.GetChildWorkflows(ctx, parentID)
+}
+
+// CancelWorkflow cancels a workflow that has not finished yet, together with
+// its child workflows. Tasks that have not started are cancelled; running
+// tasks finish but no further step is scheduled
+func (s *workflowService) CancelWorkflow(ctx context.Context, id string) error {
+	return s.repo.WithTx(ctx, func(ctx context.Context) error {
+		return s.cancelWorkflow(ctx, id, "")
+	})
+}
+
+// cancelWorkflow cancels one instance and recurses into its children.
+// cancelledBy is the parent being cancelled ("" for the workflow requested)
+func (s *workflowService) cancelWorkflow(ctx context.Context, id string, cancelledBy string) error {
+	wf, err := s.repo.LockWorkflow(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := s.repo.TransitionWorkflowStatus(ctx, id, "CANCELLED", "SCHEDULED", "PENDING", "RUNNING")
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return port.ErrWorkflowNotActive
+	}
+
+	if err := s.repo.UpdatePendingTasksStatus(ctx, id, "CANCELLED"); err != nil {
+		return err
+	}
+
+	details := map[string]any{
+		"workflow_id":   wf.ID,
+		"workflow_name": wf.WorkflowName,
+		"status":        "cancelled",
+	}
+	if cancelledBy != "" {
+		details["cancelled_by"] = cancelledBy
+	}
+	detailsJSON, _ := json.Marshal(details)
+	detailsStr := string(detailsJSON)
+	eventType := "WORKFLOW_CANCELLED"
+	if err := s.repo.CreateActivityLog(ctx, &model.ActivityLogs{
+		WorkflowInstanceID: wf.ID,
+		EventType:          &eventType,
+		Details:            &detailsStr,
+	}); err != nil {
+		return err
+	}
+
+	// Cancellation goes down to every child that is still running
+	children, err := s.repo.GetChildWorkflows(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		err := s.cancelWorkflow(ctx, child.ID, id)
+		if err != nil && !errors.Is(err, port.ErrWorkflowNotActive) {
+			return err
+		}
+	}
+
+	// A cancelled child fails the step of its parent that waits for it
+	if wf.ParentTaskID != nil && cancelledBy == "" {
+		if _, err := s.repo.WakeTask(ctx, int(*wf.ParentTaskID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendSignal delivers an external event to a running workflow. The signal is
+// stored even if no step waits for it yet, and any step already waiting for
+// it is requeued
+func (s *workflowService) SendSignal(ctx context.Context, id string, name string, payload map[string]any) error {
+	return s.repo.WithTx(ctx, func(ctx context.Context) error {
+		wf, err := s.repo.LockWorkflow(ctx, id)
+		if err != nil {
+			return err
+		}
+		if wf.Status != nil &&
+			*wf.Status != model.WorkflowInstancesStatus_Scheduled &&
+			*wf.Status != model.WorkflowInstancesStatus_Pending &&
+			*wf.Status != model.WorkflowInstancesStatus_Running {
+			return port.ErrWorkflowNotActive
+		}
+
+		def, exists := s.registry.GetDefinitionVersion(wf.WorkflowName, int(wf.WorkflowVersion))
+		if !exists || !def.WaitsForSignal(name) {
+			return port.ErrUnknownSignal
+		}
+
+		var payloadStr *string
+		if payload != nil {
+			payloadJSON, _ := json.Marshal(payload)
+			str := string(payloadJSON)
+			payloadStr = &str
+		}
+
+		if err := s.repo.CreateSignal(ctx, &model.WorkflowSignals{
+			WorkflowInstanceID: id,
+			SignalName:         name,
+			Payload:            payloadStr,
+		}); err != nil {
+			return err
+		}
+
+		// Requeue steps parked on this signal (or deferred until its timeout).
+		// A SCHEDULED instance has no waiting step yet; the stored signal is
+		// found when the step runs after start_at
+		if wf.Status == nil || *wf.Status != model.WorkflowInstancesStatus_Scheduled {
+			tasks, err := s.repo.GetTasksByWorkflowID(ctx, id)
+			if err != nil {
+				return err
+			}
+			waiting := map[string]bool{}
+			for _, taskName := range def.SignalTasks(name) {
+				waiting[taskName] = true
+			}
+			for _, task := range tasks {
+				if !waiting[task.TaskName] {
+					continue
+				}
+				if _, err := s.repo.ResumeTask(ctx, int(task.ID)); err != nil {
+					return err
+				}
+			}
+		}
+
+		detailsJSON, _ := json.Marshal(map[string]any{
+			"workflow_id": id,
+			"signal_name": name,
+			"payload":     payload,
+		})
+		detailsStr := string(detailsJSON)
+		eventType := "SIGNAL_RECEIVED"
+
+		return s.repo.CreateActivityLog(ctx, &model.ActivityLogs{
+			WorkflowInstanceID: id,
+			EventType:          &eventType,
+			Details:            &detailsStr,
+		})
+	})
+}
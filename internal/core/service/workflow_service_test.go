@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+)
+
+func TestStartTime(t *testing.T) {
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	future, past := now.Add(time.Hour), now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		req     port.CreateWorkflowRequest
+		want    *time.Time
+		wantErr bool
+	}{
+		{"right away", port.CreateWorkflowRequest{}, nil, false},
+		{"start_at", port.CreateWorkflowRequest{StartAt: &future}, &future, false},
+		{"start_at in the past", port.CreateWorkflowRequest{StartAt: &past}, nil, false},
+		{"delay", port.CreateWorkflowRequest{Delay: "1h"}, &future, false},
+		{"zero delay", port.CreateWorkflowRequest{Delay: "0s"}, nil, true},
+		{"negative delay", port.CreateWorkflowRequest{Delay: "-5m"}, nil, true},
+		{"invalid delay", port.CreateWorkflowRequest{Delay: "soon"}, nil, true},
+		{"both", port.CreateWorkflowRequest{StartAt: &future, Delay: "1h"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := startTime(&tt.req, now)
+			if tt.wantErr {
+				if !errors.Is(err, port.ErrInvalidStartTime) {
+					t.Fatalf("startTime error = %v, want ErrInvalidStartTime", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("startTime: %v", err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("startTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestHash(t *testing.T) {
+	startAt := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	sameInstant := startAt.In(time.FixedZone("ICT", 7*60*60))
+	base := func() *port.CreateWorkflowRequest {
+		return &port.CreateWorkflowRequest{
+			WorkflowName: "OrderProcess",
+			InputPayload: map[string]any{"order_id": "ORD-1", "amount": 100},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *port.CreateWorkflowRequest)
+		same   bool
+	}{
+		{"identical", func(req *port.CreateWorkflowRequest) {}, true},
+		{"key order does not matter", func(req *port.CreateWorkflowRequest) {
+			req.InputPayload = map[string]any{"amount": 100, "order_id": "ORD-1"}
+		}, true},
+		{"idempotency key is not hashed", func(req *port.CreateWorkflowRequest) {
+			req.IdempotencyKey = "other"
+		}, true},
+		{"different workflow", func(req *port.CreateWorkflowRequest) {
+			req.WorkflowName = "RefundProcess"
+		}, false},
+		{"different input", func(req *port.CreateWorkflowRequest) {
+			req.InputPayload["amount"] = 101
+		}, false},
+		{"delay", func(req *port.CreateWorkflowRequest) {
+			req.Delay = "1h"
+		}, false},
+		{"start_at", func(req *port.CreateWorkflowRequest) {
+			req.StartAt = &startAt
+		}, false},
+	}
+
+	want := requestHash(base())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base()
+			tt.modify(req)
+
+			if got := requestHash(req); (got == want) != tt.same {
+				t.Errorf("requestHash equal = %v, want %v", got == want, tt.same)
+			}
+		})
+	}
+
+	// The same instant in another zone is the same request
+	a, b := base(), base()
+	a.StartAt, b.StartAt = &startAt, &sameInstant
+	if requestHash(a) != requestHash(b) {
+		t.Error("requestHash differs for the same start_at in another time zone")
+	}
+}
+
+// signalRepo records what SendSignal does to one instance and its tasks
+type signalRepo struct {
+	port.WorkflowRepository
+	wf      *model.WorkflowInstances
+	tasks   []model.Tasks
+	signals []*model.WorkflowSignals
+	resumed []int
+}
+
+func (r *signalRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (r *signalRepo) LockWorkflow(ctx context.Context, id string) (*model.WorkflowInstances, error) {
+	return r.wf, nil
+}
+
+func (r *signalRepo) CreateSignal(ctx context.Context, signal *model.WorkflowSignals) error {
+	r.signals = append(r.signals, signal)
+	return nil
+}
+
+func (r *signalRepo) GetTasksByWorkflowID(ctx context.Context, wfID string) ([]model.Tasks, error) {
+	return r.tasks, nil
+}
+
+func (r *signalRepo) ResumeTask(ctx context.Context, id int) (bool, error) {
+	r.resumed = append(r.resumed, id)
+	return true, nil
+}
+
+func (r *signalRepo) CreateActivityLog(ctx context.Context, log *model.ActivityLogs) error {
+	return nil
+}
+
+func TestSendSignal(t *testing.T) {
+	reg := registry.NewWorkflowRegistry()
+	reg.NewWorkflow("Approval").
+		AddSignalWait("Approve", "approved").
+		AddTask("Ship", func(ctx context.Context, task *model.Tasks) error { return nil }).
+		MustBuild()
+
+	tests := []struct {
+		name        string
+		status      model.WorkflowInstancesStatus
+		wantResumed []int
+	}{
+		// The root step waits for start_at; the signal must not make it claimable
+		{"scheduled instance", model.WorkflowInstancesStatus_Scheduled, nil},
+		{"running instance", model.WorkflowInstancesStatus_Running, []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			startAt := time.Now().Add(time.Hour)
+			repo := &signalRepo{
+				wf: &model.WorkflowInstances{ID: "wf-1", WorkflowName: "Approval", WorkflowVersion: 1, Status: &status},
+				tasks: []model.Tasks{
+					{ID: 1, WorkflowInstanceID: "wf-1", TaskName: "Approve", ScheduledAt: &startAt},
+				},
+			}
+
+			err := NewWorkflowService(repo, reg).SendSignal(context.Background(), "wf-1", "approved", map[string]any{"by": "ops"})
+			if err != nil {
+				t.Fatalf("SendSignal: %v", err)
+			}
+			if len(repo.signals) != 1 || repo.signals[0].SignalName != "approved" {
+				t.Errorf("stored signals = %v, want one approved signal", repo.signals)
+			}
+			if !reflect.DeepEqual(repo.resumed, tt.wantResumed) {
+				t.Errorf("resumed tasks = %v, want %v", repo.resumed, tt.wantResumed)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+)
+
+// logActivity writes an activity log entry for a workflow, and for a task
+// when taskName is set
+func (w *WorkflowWorker) logActivity(ctx context.Context, wfID string, taskName *string, eventType string, details map[string]any) error {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	detailsStr := string(detailsJSON)
+
+	return w.repo.CreateActivityLog(ctx, &model.ActivityLogs{
+		WorkflowInstanceID: wfID,
+		TaskName:           taskName,
+		EventType:          &eventType,
+		Details:            &detailsStr,
+	})
+}
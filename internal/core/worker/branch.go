@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// takeBranch evaluates the branch cases of a completed task against its
+// output and writes a BRANCH_TAKEN activity log. It returns the task to
+// start next, or "" when the path ends
+func (w *WorkflowWorker) takeBranch(ctx context.Context, task model.Tasks, taskDef *registry.TaskDefinition) (string, error) {
+	output := map[string]any{}
+	if task.OutputPayload != nil {
+		// Non-object outputs leave the map empty; predicates see no fields
+		json.Unmarshal([]byte(*task.OutputPayload), &output)
+	}
+
+	next, index, matched, err := selectBranch(taskDef, output)
+	if err != nil {
+		return "", err
+	}
+
+	branch := next
+	if !matched || next == registry.End {
+		branch = "END"
+		next = ""
+	}
+
+	logger.Info().
+		Str("task_name", task.TaskName).
+		Str("workflow_id", task.WorkflowInstanceID).
+		Str("branch", branch).
+		Msg("Branch taken")
+
+	err = w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "BRANCH_TAKEN", map[string]any{
+		"task_id":    task.ID,
+		"task_name":  task.TaskName,
+		"branch":     branch,
+		"case_index": index,
+		"matched":    matched,
+	})
+
+	return next, err
+}
+
+// selectBranch runs the predicates, turning a panicking predicate into an
+// error so the transition is rolled back instead of crashing the worker
+func selectBranch(taskDef *registry.TaskDefinition, output map[string]any) (next string, index int, matched bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("branch predicate of %s panicked: %v", taskDef.Name, r)
+		}
+	}()
+
+	next, index, matched = taskDef.SelectBranch(output)
+
+	return next, index, matched, nil
+}
@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// isWorkflowFinished reports whether a workflow reached a final status
+func isWorkflowFinished(wf *model.WorkflowInstances) bool {
+	if wf.Status == nil {
+		return false
+	}
+
+	switch *wf.Status {
+	case model.WorkflowInstancesStatus_Completed,
+		model.WorkflowInstancesStatus_Failed,
+		model.WorkflowInstancesStatus_Compensated,
+		model.WorkflowInstancesStatus_Cancelled:
+		return true
+	}
+
+	return false
+}
+
+// runChildWorkflow executes a child workflow step. The first run starts the
+// child and parks the task in WAITING; when the child finishes the task is
+// woken and completes with the child's output, or fails if the child did not
+// complete
+func (w *WorkflowWorker) runChildWorkflow(ctx context.Context, wf *model.WorkflowInstances, task model.Tasks, taskDef *registry.TaskDefinition, retryCount int32, policy registry.RetryPolicy) {
+	var finished *model.WorkflowInstances
+
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		children, err := w.repo.GetChildWorkflows(ctx, wf.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			if child.ParentTaskID == nil || *child.ParentTaskID != task.ID {
+				continue
+			}
+
+			// Lock the child so it cannot finish between this check and parking
+			locked, err := w.repo.LockWorkflow(ctx, child.ID)
+			if err != nil {
+				return err
+			}
+			if isWorkflowFinished(locked) {
+				finished = locked
+				return nil
+			}
+
+			return w.parkTask(ctx, task)
+		}
+
+		return w.startChildWorkflow(ctx, wf, task, taskDef)
+	})
+	if errors.Is(err, errLeaseLost) {
+		logger.Warn().Int64("task_id", task.ID).Msg("Task lease lost before waiting for child workflow")
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Str("child_workflow", taskDef.ChildWorkflow).Msg("Failed to start child workflow")
+		w.handleTaskFailure(ctx, task, retryCount, policy, err)
+		return
+	}
+	if finished == nil {
+		return
+	}
+
+	if *finished.Status != model.WorkflowInstancesStatus_Completed {
+		err := registry.NonRetryable(fmt.Errorf("child workflow %s %s", finished.ID, *finished.Status))
+		w.handleTaskFailure(ctx, task, retryCount, policy, err)
+		return
+	}
+
+	task.OutputPayload = finished.CurrentOutput
+	w.handleTaskSuccess(ctx, task, retryCount)
+}
+
+// startChildWorkflow creates the child instance with the step's input and
+// parks the step until the child finishes
+func (w *WorkflowWorker) startChildWorkflow(ctx context.Context, wf *model.WorkflowInstances, task model.Tasks, taskDef *registry.TaskDefinition) error {
+	input := map[string]any{}
+	if task.InputPayload != nil {
+		if err := json.Unmarshal([]byte(*task.InputPayload), &input); err != nil {
+			return registry.NonRetryable(fmt.Errorf("decode child workflow input: %w", err))
+		}
+	}
+
+	if err := w.parkTask(ctx, task); err != nil {
+		return err
+	}
+
+	child, err := w.svc.StartNewWorkflow(ctx, &port.CreateWorkflowRequest{
+		WorkflowName: taskDef.ChildWorkflow,
+		InputPayload: input,
+		ParentID:     &wf.ID,
+		ParentTaskID: &task.ID,
+	})
+	var inputErr *port.InputValidationError
+	if errors.As(err, &inputErr) {
+		// The same input would be rejected again on every retry
+		return registry.NonRetryable(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info().
+		Str("workflow_id", wf.ID).
+		Str("task_name", task.TaskName).
+		Str("child_workflow_id", child.ID).
+		Str("child_workflow_name", child.WorkflowName).
+		Msg("Child workflow started")
+
+	return w.logActivity(ctx, wf.ID, &task.TaskName, "CHILD_WORKFLOW_STARTED", map[string]any{
+		"task_id":             task.ID,
+		"task_name":           task.TaskName,
+		"child_workflow_id":   child.ID,
+		"child_workflow_name": child.WorkflowName,
+	})
+}
+
+// parkTask releases the task into WAITING; errLeaseLost if the claim is gone
+func (w *WorkflowWorker) parkTask(ctx context.Context, task model.Tasks) error {
+	parked, err := w.repo.ParkTask(ctx, int(task.ID), w.workerID)
+	if err != nil {
+		return err
+	}
+	if !parked {
+		return errLeaseLost
+	}
+
+	return nil
+}
+
+// wakeParent requeues the parent step waiting for a workflow that just finished
+func (w *WorkflowWorker) wakeParent(ctx context.Context, wf *model.WorkflowInstances) error {
+	if wf.ParentTaskID == nil {
+		return nil
+	}
+
+	_, err := w.repo.WakeTask(ctx, int(*wf.ParentTaskID))
+
+	return err
+}
+
+// cancelChildren cancels the running children of a workflow that failed
+func (w *WorkflowWorker) cancelChildren(ctx context.Context, wf *model.WorkflowInstances) error {
+	children, err := w.repo.GetChildWorkflows(ctx, wf.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if isWorkflowFinished(&child) {
+			continue
+		}
+		err := w.svc.CancelWorkflow(ctx, child.ID)
+		if err != nil && !errors.Is(err, port.ErrWorkflowNotActive) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endWorkflow sets the final status of a workflow and wakes its parent step
+func (w *WorkflowWorker) endWorkflow(ctx context.Context, wf *model.WorkflowInstances, status string) error {
+	if err := w.repo.UpdateWorkflowStatus(ctx, wf.ID, status); err != nil {
+		return err
+	}
+
+	return w.wakeParent(ctx, wf)
+}
@@ -0,0 +1,281 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// isCompensating reports whether the workflow is undoing its completed steps
+func isCompensating(wf *model.WorkflowInstances) bool {
+	return wf.Status != nil && *wf.Status == model.WorkflowInstancesStatus_Compensating
+}
+
+// canRunTask reports whether a claimed task may still run: normal tasks need
+// an active workflow, compensation tasks a compensating one
+func canRunTask(wf *model.WorkflowInstances, task model.Tasks) bool {
+	if _, isCompensation := registry.CompensatedTaskName(task.TaskName); isCompensation {
+		return isCompensating(wf)
+	}
+
+	return isWorkflowActive(wf)
+}
+
+// resolveTaskFunc returns the function to run for a task row, which is the
+// step's compensation for compensation tasks
+func resolveTaskFunc(def *registry.WorkflowDefinition, taskName string) (registry.TaskFunc, bool) {
+	if original, isCompensation := registry.CompensatedTaskName(taskName); isCompensation {
+		return def.CompensationFunc(original)
+	}
+
+	return def.TaskFunc(taskName)
+}
+
+// compensableTasks returns the completed steps that have a compensation,
+// most recent first. Map steps are compensated item by item
+func compensableTasks(def *registry.WorkflowDefinition, tasks []model.Tasks) []model.Tasks {
+	steps := []model.Tasks{}
+	for _, t := range tasks {
+		name := t.TaskName
+		step, _, isItem := registry.MapItemStep(name)
+		if isItem {
+			name = step
+		}
+		taskDef, exists := def.Tasks[name]
+		if !exists || taskDef.Compensation == nil || (taskDef.Map != nil && !isItem) {
+			continue
+		}
+		if t.Status != nil && *t.Status == model.TasksStatus_Completed {
+			steps = append(steps, t)
+		}
+	}
+
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].ID > steps[j].ID
+	})
+
+	return steps
+}
+
+// failWorkflow ends a workflow whose task failed permanently. Completed steps
+// with a compensation are undone first; without any the workflow is FAILED
+// right away. It runs inside the caller's transaction with wf locked
+func (w *WorkflowWorker) failWorkflow(ctx context.Context, wf *model.WorkflowInstances, task model.Tasks) error {
+	if original, isCompensation := registry.CompensatedTaskName(task.TaskName); isCompensation {
+		// A compensation that cannot complete needs manual intervention
+		logger.Error().
+			Str("workflow_id", wf.ID).
+			Str("task_name", original).
+			Msg("Compensation FAILED")
+
+		if err := w.endWorkflow(ctx, wf, "FAILED"); err != nil {
+			return err
+		}
+		return w.logActivity(ctx, wf.ID, &task.TaskName, "COMPENSATION_FAILED", map[string]any{
+			"workflow_id": wf.ID,
+			"task_id":     task.ID,
+			"task_name":   original,
+			"status":      "failed",
+		})
+	}
+
+	if isCompensating(wf) {
+		// A parallel step failed while compensation was already running
+		return w.continueCompensation(ctx, wf)
+	}
+	if !isWorkflowActive(wf) {
+		return nil
+	}
+
+	// A map step tolerating failed items just moves on to its next item
+	if w.continuesOnError(wf, task) {
+		return w.advanceMap(ctx, wf, task)
+	}
+
+	// Child workflows of other steps are no longer needed
+	if err := w.cancelChildren(ctx, wf); err != nil {
+		return err
+	}
+
+	started, err := w.startCompensation(ctx, wf, &task, "task_failed")
+	if err != nil || started {
+		return err
+	}
+
+	// Mark workflow as FAILED; tasks of parallel branches that have not
+	// started yet will never run
+	if err := w.repo.UpdatePendingTasksStatus(ctx, wf.ID, "FAILED"); err != nil {
+		return err
+	}
+	return w.endWorkflow(ctx, wf, "FAILED")
+}
+
+// startCompensation moves the workflow to COMPENSATING and schedules the
+// compensation of its most recent completed step. failedTask is nil when the
+// workflow timed out between tasks. It reports false when no completed step
+// has a compensation
+func (w *WorkflowWorker) startCompensation(ctx context.Context, wf *model.WorkflowInstances, failedTask *model.Tasks, reason string) (bool, error) {
+	def, err := w.definitionFor(wf)
+	if err != nil {
+		return false, nil
+	}
+
+	tasks, err := w.repo.GetTasksByWorkflowID(ctx, wf.ID)
+	if err != nil {
+		return false, fmt.Errorf("get tasks: %w", err)
+	}
+
+	steps := compensableTasks(def, tasks)
+	if len(steps) == 0 {
+		return false, nil
+	}
+
+	if _, err := w.repo.TransitionWorkflowStatus(ctx, wf.ID, "COMPENSATING", "SCHEDULED", "PENDING", "RUNNING"); err != nil {
+		return false, err
+	}
+	status := model.WorkflowInstancesStatus_Compensating
+	wf.Status = &status
+
+	// Steps that have not started yet will never run
+	if err := w.repo.UpdatePendingTasksStatus(ctx, wf.ID, "FAILED"); err != nil {
+		return false, err
+	}
+
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.TaskName)
+	}
+
+	details := map[string]any{
+		"workflow_id": wf.ID,
+		"reason":      reason,
+		"steps":       names,
+	}
+	var taskName *string
+	if failedTask != nil {
+		taskName = &failedTask.TaskName
+		details["failed_task"] = failedTask.TaskName
+	}
+
+	logger.Warn().
+		Str("workflow_id", wf.ID).
+		Str("reason", reason).
+		Strs("steps", names).
+		Msg("Workflow COMPENSATING")
+
+	if err := w.logActivity(ctx, wf.ID, taskName, "COMPENSATION_STARTED", details); err != nil {
+		return false, err
+	}
+
+	return true, w.continueCompensation(ctx, wf)
+}
+
+// continueCompensation schedules the next compensation, one at a time in
+// reverse order of the steps. Once every completed step is compensated and
+// nothing is running any more, the workflow ends COMPENSATED
+func (w *WorkflowWorker) continueCompensation(ctx context.Context, wf *model.WorkflowInstances) error {
+	def, err := w.definitionFor(wf)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := w.repo.GetTasksByWorkflowID(ctx, wf.ID)
+	if err != nil {
+		return fmt.Errorf("get tasks: %w", err)
+	}
+	byName := tasksByName(tasks)
+
+	// The running compensation schedules the next one when it completes
+	for _, t := range tasks {
+		if _, isCompensation := registry.CompensatedTaskName(t.TaskName); isCompensation && isTaskActive(t) {
+			return nil
+		}
+	}
+
+	steps := compensableTasks(def, tasks)
+	for _, step := range steps {
+		taskName := registry.CompensationTaskName(step.TaskName)
+		if _, exists := byName[taskName]; exists {
+			continue
+		}
+
+		// The compensation sees what the step produced
+		input := step.OutputPayload
+		if input == nil {
+			input = step.InputPayload
+		}
+
+		status := model.TasksStatus_Pending
+		logger.Info().Str("next_task", taskName).Str("workflow_id", wf.ID).Msg("Compensating step")
+
+		err := w.repo.CreateTask(ctx, &model.Tasks{
+			WorkflowInstanceID: wf.ID,
+			TaskName:           taskName,
+			Status:             &status,
+			InputPayload:       input,
+		})
+		if errors.Is(err, port.ErrTaskAlreadyExists) {
+			return nil
+		}
+		return err
+	}
+
+	// A step of a parallel path is still running; it may need compensating too
+	for _, t := range tasks {
+		if isTaskActive(t) {
+			return nil
+		}
+	}
+
+	logger.Info().Str("workflow_name", wf.WorkflowName).Str("workflow_id", wf.ID).Msg("Workflow COMPENSATED")
+
+	if err := w.endWorkflow(ctx, wf, "COMPENSATED"); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.TaskName)
+	}
+
+	return w.logActivity(ctx, wf.ID, nil, "COMPENSATION_COMPLETED", map[string]any{
+		"workflow_id":   wf.ID,
+		"workflow_name": wf.WorkflowName,
+		"compensated":   names,
+		"status":        "compensated",
+	})
+}
+
+// skipTask fails (or cancels) a claimed task that can no longer run because
+// its workflow has finished or is compensating
+func (w *WorkflowWorker) skipTask(ctx context.Context, task model.Tasks) {
+	logger.Warn().Int64("task_id", task.ID).Str("workflow_id", task.WorkflowInstanceID).Msg("Skipping task of finished workflow")
+
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		wf, err := w.repo.LockWorkflow(ctx, task.WorkflowInstanceID)
+		if err != nil {
+			return err
+		}
+		status := "FAILED"
+		if wf.Status != nil && *wf.Status == model.WorkflowInstancesStatus_Cancelled {
+			status = "CANCELLED"
+		}
+		if err := w.repo.UpdateTaskStatus(ctx, int(task.ID), status); err != nil {
+			return err
+		}
+		if isCompensating(wf) {
+			// This may have been the last task compensation was waiting for
+			return w.continueCompensation(ctx, wf)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to skip task")
+	}
+}
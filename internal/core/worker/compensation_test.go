@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+)
+
+func TestWorkerCompensatesCompletedSteps(t *testing.T) {
+	var released []string
+	release := func(name string) registry.TaskFunc {
+		return func(ctx context.Context, task *model.Tasks) error {
+			released = append(released, name)
+			return nil
+		}
+	}
+	ok := func(ctx context.Context, task *model.Tasks) error { return nil }
+
+	w, repo := newTestWorker(t, func(reg *registry.WorkflowRegistry) {
+		reg.NewWorkflow("Order").
+			AddTask("Reserve", ok, registry.WithCompensation(release("Reserve"))).
+			AddTask("Hold", ok, registry.WithCompensation(release("Hold"))).
+			AddTask("Charge", func(ctx context.Context, task *model.Tasks) error {
+				return registry.NonRetryable(errors.New("card declined"))
+			}).
+			MustBuild()
+	})
+	wfID := repo.startWorkflow("Order", "Reserve", "{}")
+
+	// Reserve, Hold แล้ว Charge fail
+	for i := 0; i < 3; i++ {
+		runReady(t, w, repo)
+	}
+	if got := workflowStatus(repo.workflows[wfID]); got != model.WorkflowInstancesStatus_Compensating {
+		t.Fatalf("workflow status = %s, want COMPENSATING", got)
+	}
+
+	for runReady(t, w, repo) > 0 {
+	}
+
+	// ย้อนกลับจาก step ล่าสุดไปหา step แรก
+	if want := []string{"Hold", "Reserve"}; !reflect.DeepEqual(released, want) {
+		t.Errorf("compensated %v, want %v", released, want)
+	}
+	for _, name := range []string{"Hold", "Reserve"} {
+		task := repo.task(t, wfID, registry.CompensationTaskName(name))
+		if got := taskStatus(task); got != model.TasksStatus_Completed {
+			t.Errorf("%s status = %s, want COMPLETED", task.TaskName, got)
+		}
+	}
+	if got := workflowStatus(repo.workflows[wfID]); got != model.WorkflowInstancesStatus_Compensated {
+		t.Errorf("workflow status = %s, want COMPENSATED", got)
+	}
+
+	want := []string{
+		"TASK_STARTED", "WORKFLOW_STARTED", "TASK_COMPLETED",
+		"TASK_STARTED", "TASK_COMPLETED",
+		"TASK_STARTED", "TASK_FAILED", "COMPENSATION_STARTED",
+		"TASK_STARTED", "TASK_COMPLETED",
+		"TASK_STARTED", "TASK_COMPLETED", "COMPENSATION_COMPLETED",
+	}
+	if got := repo.events(wfID); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
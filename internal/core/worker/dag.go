@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+)
+
+// tasksByName indexes the tasks of one workflow instance by task name
+func tasksByName(tasks []model.Tasks) map[string]model.Tasks {
+	byName := make(map[string]model.Tasks, len(tasks))
+	for _, t := range tasks {
+		byName[t.TaskName] = t
+	}
+
+	return byName
+}
+
+// isTaskActive reports whether a task is still waiting to run, running or
+// waiting on a child workflow
+func isTaskActive(task model.Tasks) bool {
+	if task.Status == nil {
+		return true
+	}
+
+	switch *task.Status {
+	case model.TasksStatus_Pending, model.TasksStatus_InProgress, model.TasksStatus_Retrying, model.TasksStatus_Waiting:
+		return true
+	}
+
+	return false
+}
+
+// dependenciesCompleted reports whether every task in deps has completed
+func dependenciesCompleted(byName map[string]model.Tasks, deps []string) bool {
+	for _, dep := range deps {
+		t, exists := byName[dep]
+		if !exists || t.Status == nil || *t.Status != model.TasksStatus_Completed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeOutputs builds the input of a task from the outputs of the given
+// tasks. A single output is passed through unchanged; several outputs are
+// shallow-merged in order (later keys win), with non-object outputs stored
+// under their task name
+func mergeOutputs(byName map[string]model.Tasks, names []string) (*string, error) {
+	if len(names) == 1 {
+		return byName[names[0]].OutputPayload, nil
+	}
+
+	merged := map[string]any{}
+	for _, name := range names {
+		output := byName[name].OutputPayload
+		if output == nil {
+			continue
+		}
+
+		var value any
+		if err := json.Unmarshal([]byte(*output), &value); err != nil {
+			return nil, fmt.Errorf("decode output of %s: %w", name, err)
+		}
+		if obj, ok := value.(map[string]any); ok {
+			for k, v := range obj {
+				merged[k] = v
+			}
+			continue
+		}
+		merged[name] = value
+	}
+
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	mergedStr := string(mergedJSON)
+
+	return &mergedStr, nil
+}
+
+// outputTasks returns the completed tasks that ended a path: no created task
+// depends on them and they did not branch into a created task
+func outputTasks(def *registry.WorkflowDefinition, tasks []model.Tasks) []string {
+	byName := tasksByName(tasks)
+	consumed := map[string]bool{}
+	for _, t := range tasks {
+		if taskDef, exists := def.Tasks[t.TaskName]; exists {
+			for _, dep := range taskDef.DependsOn {
+				consumed[dep] = true
+			}
+		}
+	}
+
+	names := []string{}
+	for _, t := range tasks {
+		if consumed[t.TaskName] || t.Status == nil || *t.Status != model.TasksStatus_Completed {
+			continue
+		}
+		if _, _, isItem := registry.MapItemStep(t.TaskName); isItem {
+			// Items are collected by their map step
+			continue
+		}
+		if taskDef, exists := def.Tasks[t.TaskName]; exists && branchedInto(taskDef, byName) {
+			continue
+		}
+		names = append(names, t.TaskName)
+	}
+
+	return names
+}
+
+// branchedInto reports whether one of the task's route targets was created
+func branchedInto(taskDef *registry.TaskDefinition, byName map[string]model.Tasks) bool {
+	for _, target := range taskDef.RouteTargets() {
+		if _, exists := byName[target]; exists {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,338 @@
+package worker
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/port"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+)
+
+// fakeRepo is an in-memory WorkflowRepository for driving the worker's state
+// machine. Methods the tests don't reach are left to the embedded nil
+// interface and panic. WithTx rolls back on error like the MySQL one
+type fakeRepo struct {
+	port.WorkflowRepository
+	workflows map[string]model.WorkflowInstances
+	tasks     map[int64]model.Tasks
+	logs      []model.ActivityLogs
+	nextID    int64
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		workflows: map[string]model.WorkflowInstances{},
+		tasks:     map[int64]model.Tasks{},
+	}
+}
+
+func (r *fakeRepo) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	workflows := make(map[string]model.WorkflowInstances, len(r.workflows))
+	for id, wf := range r.workflows {
+		workflows[id] = wf
+	}
+	tasks := make(map[int64]model.Tasks, len(r.tasks))
+	for id, task := range r.tasks {
+		tasks[id] = task
+	}
+	logs := len(r.logs)
+
+	if err := fn(ctx); err != nil {
+		r.workflows, r.tasks, r.logs = workflows, tasks, r.logs[:logs]
+		return err
+	}
+
+	return nil
+}
+
+func (r *fakeRepo) GetWorkflowByID(ctx context.Context, id string) (*model.WorkflowInstances, error) {
+	wf := r.workflows[id]
+	return &wf, nil
+}
+
+func (r *fakeRepo) LockWorkflow(ctx context.Context, id string) (*model.WorkflowInstances, error) {
+	return r.GetWorkflowByID(ctx, id)
+}
+
+func (r *fakeRepo) GetChildWorkflows(ctx context.Context, parentID string) ([]model.WorkflowInstances, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) setWorkflow(id string, update func(wf *model.WorkflowInstances)) {
+	wf := r.workflows[id]
+	update(&wf)
+	r.workflows[id] = wf
+}
+
+func (r *fakeRepo) UpdateWorkflowStatus(ctx context.Context, id string, status string) error {
+	r.setWorkflow(id, func(wf *model.WorkflowInstances) {
+		s := model.WorkflowInstancesStatus(status)
+		wf.Status = &s
+	})
+	return nil
+}
+
+func (r *fakeRepo) TransitionWorkflowStatus(ctx context.Context, id string, status string, from ...string) (bool, error) {
+	wf := r.workflows[id]
+	for _, f := range from {
+		if wf.Status != nil && string(*wf.Status) == f {
+			return true, r.UpdateWorkflowStatus(ctx, id, status)
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRepo) UpdateWorkflowOutput(ctx context.Context, id string, output string) error {
+	r.setWorkflow(id, func(wf *model.WorkflowInstances) { wf.CurrentOutput = &output })
+	return nil
+}
+
+func (r *fakeRepo) UpdateWorkflowState(ctx context.Context, id string, state string) error {
+	r.setWorkflow(id, func(wf *model.WorkflowInstances) { wf.State = &state })
+	return nil
+}
+
+func (r *fakeRepo) CreateTask(ctx context.Context, task *model.Tasks) error {
+	for _, t := range r.tasks {
+		if t.WorkflowInstanceID == task.WorkflowInstanceID && t.TaskName == task.TaskName {
+			return port.ErrTaskAlreadyExists
+		}
+	}
+
+	r.nextID++
+	task.ID = r.nextID
+	if task.RetryCount == nil {
+		task.RetryCount = new(int32)
+	}
+	r.tasks[task.ID] = *task
+	return nil
+}
+
+func (r *fakeRepo) GetTasksByWorkflowID(ctx context.Context, wfID string) ([]model.Tasks, error) {
+	tasks := []model.Tasks{}
+	for _, t := range r.tasks {
+		if t.WorkflowInstanceID == wfID {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (r *fakeRepo) ClaimTasks(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Tasks, error) {
+	now := time.Now()
+	claimed := []model.Tasks{}
+	for _, t := range r.tasks {
+		if len(claimed) == limit {
+			break
+		}
+		if taskStatus(t) != model.TasksStatus_Pending || (t.ScheduledAt != nil && t.ScheduledAt.After(now)) {
+			continue
+		}
+
+		r.setTask(t.ID, "IN_PROGRESS", func(t *model.Tasks) {
+			expires := now.Add(lease)
+			t.ClaimedBy, t.ClaimedAt, t.LeaseExpiresAt = &workerID, &now, &expires
+		})
+		claimed = append(claimed, r.tasks[t.ID])
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed, nil
+}
+
+func (r *fakeRepo) RenewTaskLease(ctx context.Context, id int, workerID string, lease time.Duration) (bool, error) {
+	return r.claimed(id, workerID), nil
+}
+
+// claimed mirrors claimedTaskCondition of the MySQL repository
+func (r *fakeRepo) claimed(id int, workerID string) bool {
+	t, exists := r.tasks[int64(id)]
+	status := taskStatus(t)
+	return exists && t.ClaimedBy != nil && *t.ClaimedBy == workerID &&
+		(status == model.TasksStatus_InProgress || status == model.TasksStatus_Retrying)
+}
+
+func (r *fakeRepo) setTask(id int64, status string, update func(t *model.Tasks)) {
+	t := r.tasks[id]
+	if status != "" {
+		s := model.TasksStatus(status)
+		t.Status = &s
+	}
+	if update != nil {
+		update(&t)
+	}
+	r.tasks[id] = t
+}
+
+func releaseClaim(t *model.Tasks) {
+	t.ClaimedBy, t.ClaimedAt, t.LeaseExpiresAt = nil, nil, nil
+}
+
+func (r *fakeRepo) MarkTaskRetrying(ctx context.Context, id int, workerID string) (bool, error) {
+	if !r.claimed(id, workerID) {
+		return false, nil
+	}
+	r.setTask(int64(id), "RETRYING", nil)
+	return true, nil
+}
+
+func (r *fakeRepo) CompleteTask(ctx context.Context, id int, workerID string, output *string) (bool, error) {
+	if !r.claimed(id, workerID) {
+		return false, nil
+	}
+	r.setTask(int64(id), "COMPLETED", func(t *model.Tasks) {
+		t.OutputPayload = output
+		releaseClaim(t)
+	})
+	return true, nil
+}
+
+func (r *fakeRepo) FailTask(ctx context.Context, id int, workerID string) (bool, error) {
+	if !r.claimed(id, workerID) {
+		return false, nil
+	}
+	r.setTask(int64(id), "FAILED", releaseClaim)
+	return true, nil
+}
+
+func (r *fakeRepo) ScheduleTaskRetry(ctx context.Context, id int, workerID string, retryCount int, scheduledAt time.Time) (bool, error) {
+	if !r.claimed(id, workerID) {
+		return false, nil
+	}
+	r.setTask(int64(id), "PENDING", func(t *model.Tasks) {
+		count := int32(retryCount)
+		t.RetryCount, t.ScheduledAt = &count, &scheduledAt
+		releaseClaim(t)
+	})
+	return true, nil
+}
+
+func (r *fakeRepo) UpdateTaskErrorMessage(ctx context.Context, id int, message string) error {
+	r.setTask(int64(id), "", func(t *model.Tasks) { t.ErrorMessage = &message })
+	return nil
+}
+
+func (r *fakeRepo) UpdateTaskStatus(ctx context.Context, id int, status string) error {
+	r.setTask(int64(id), status, nil)
+	return nil
+}
+
+func (r *fakeRepo) UpdatePendingTasksStatus(ctx context.Context, wfID string, status string) error {
+	for _, t := range r.tasks {
+		if s := taskStatus(t); t.WorkflowInstanceID == wfID && (s == model.TasksStatus_Pending || s == model.TasksStatus_Waiting) {
+			r.setTask(t.ID, status, nil)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) CreateActivityLog(ctx context.Context, log *model.ActivityLogs) error {
+	r.logs = append(r.logs, *log)
+	return nil
+}
+
+// events returns the activity log event types of a workflow in order
+func (r *fakeRepo) events(wfID string) []string {
+	events := []string{}
+	for _, log := range r.logs {
+		if log.WorkflowInstanceID == wfID && log.EventType != nil {
+			events = append(events, *log.EventType)
+		}
+	}
+	return events
+}
+
+// task returns the task row of a step
+func (r *fakeRepo) task(t *testing.T, wfID, name string) model.Tasks {
+	t.Helper()
+	for _, task := range r.tasks {
+		if task.WorkflowInstanceID == wfID && task.TaskName == name {
+			return task
+		}
+	}
+	t.Fatalf("no task %s in workflow %s", name, wfID)
+	return model.Tasks{}
+}
+
+// makeReady moves a retry's scheduled_at into the past
+func (r *fakeRepo) makeReady(id int64) {
+	r.setTask(id, "", func(t *model.Tasks) {
+		past := time.Now().Add(-time.Second)
+		t.ScheduledAt = &past
+	})
+}
+
+func taskStatus(t model.Tasks) model.TasksStatus {
+	if t.Status == nil {
+		return ""
+	}
+	return *t.Status
+}
+
+func workflowStatus(wf model.WorkflowInstances) model.WorkflowInstancesStatus {
+	if wf.Status == nil {
+		return ""
+	}
+	return *wf.Status
+}
+
+// newTestWorker returns a worker on a fake repository with the workflows
+// registered by register
+func newTestWorker(t *testing.T, register func(reg *registry.WorkflowRegistry)) (*WorkflowWorker, *fakeRepo) {
+	t.Helper()
+
+	reg := registry.NewWorkflowRegistry()
+	register(reg)
+
+	repo := newFakeRepo()
+	cfg := validWorkerConfig()
+	w, err := NewWorkflowWorker(repo, reg, nil, &cfg)
+	if err != nil {
+		t.Fatalf("NewWorkflowWorker: %v", err)
+	}
+
+	return w, repo
+}
+
+// startWorkflow creates a PENDING instance with its first task, as
+// StartNewWorkflow does
+func (r *fakeRepo) startWorkflow(name, firstTask, input string) string {
+	id := "wf-" + name
+	status := model.WorkflowInstancesStatus_Pending
+	r.workflows[id] = model.WorkflowInstances{
+		ID:              id,
+		WorkflowName:    name,
+		WorkflowVersion: 1,
+		Status:          &status,
+		CurrentInput:    &input,
+	}
+
+	taskStatus := model.TasksStatus_Pending
+	r.CreateTask(context.Background(), &model.Tasks{
+		WorkflowInstanceID: id,
+		TaskName:           firstTask,
+		Status:             &taskStatus,
+		InputPayload:       &input,
+	})
+
+	return id
+}
+
+// runReady claims every ready task and executes it like a worker slot does,
+// returning how many ran
+func runReady(t *testing.T, w *WorkflowWorker, repo *fakeRepo) int {
+	t.Helper()
+
+	tasks, err := repo.ClaimTasks(context.Background(), w.workerID, 100, w.leaseDuration)
+	if err != nil {
+		t.Fatalf("ClaimTasks: %v", err)
+	}
+	for _, task := range tasks {
+		w.executeTask(context.Background(), task)
+	}
+
+	return len(tasks)
+}
@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// errLeaseLost cancels a running task whose claim was taken away by the reaper
+var errLeaseLost = errors.New("task lease lost")
+
+// startHeartbeat renews the task lease every heartbeatInterval until stopped.
+// The returned context is cancelled if the lease can no longer be renewed
+func (w *WorkflowWorker) startHeartbeat(ctx context.Context, task model.Tasks) (context.Context, func()) {
+	hbCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(w.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-hbCtx.Done():
+				return
+			case <-ticker.C:
+				held, err := w.repo.RenewTaskLease(hbCtx, int(task.ID), w.workerID, w.leaseDuration)
+				if err != nil {
+					logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to renew task lease")
+					continue
+				}
+				if !held {
+					logger.Warn().
+						Int64("task_id", task.ID).
+						Str("worker_id", w.workerID).
+						Msg("Task lease lost, cancelling execution")
+					cancel(errLeaseLost)
+					return
+				}
+			}
+		}
+	}()
+
+	return hbCtx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
+
+// runReaper periodically returns tasks with expired leases to the queue and
+// fails workflows that ran past their deadline
+func (w *WorkflowWorker) runReaper(ctx context.Context) {
+	ticker := time.NewTicker(w.reaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reapExpiredTasks(ctx)
+			w.reapTimedOutWorkflows(ctx)
+		}
+	}
+}
+
+// reapExpiredTasks moves tasks whose lease expired back to PENDING, counting
+// the lost run as an attempt. Tasks out of attempts (per their retry policy)
+// are marked FAILED
+func (w *WorkflowWorker) reapExpiredTasks(ctx context.Context) {
+	tasks, err := w.repo.GetExpiredTasks(ctx, w.batchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error fetching expired tasks")
+		return
+	}
+
+	for _, task := range tasks {
+		retryCount := int32(0)
+		if task.RetryCount != nil {
+			retryCount = *task.RetryCount
+		}
+		newRetryCount := retryCount + 1
+
+		policy := w.defaultRetryPolicy()
+		if wf, err := w.repo.GetWorkflowByID(ctx, task.WorkflowInstanceID); err == nil {
+			if def, err := w.definitionFor(wf); err == nil {
+				policy = w.retryPolicy(def, task.TaskName)
+			}
+		}
+
+		status := "PENDING"
+		if int(newRetryCount) >= policy.MaxAttempts {
+			status = "FAILED"
+		}
+
+		err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+			// Lock the instance first, in the same order as other step transitions
+			wf, err := w.repo.LockWorkflow(ctx, task.WorkflowInstanceID)
+			if err != nil {
+				return err
+			}
+
+			released, err := w.repo.ReleaseExpiredTask(ctx, task, status, int(newRetryCount))
+			if err != nil {
+				return err
+			}
+			if !released {
+				// Renewed or already reaped by another replica
+				return nil
+			}
+
+			logger.Warn().
+				Int64("task_id", task.ID).
+				Str("task_name", task.TaskName).
+				Str("workflow_id", task.WorkflowInstanceID).
+				Int32("retry_count", newRetryCount).
+				Str("status", status).
+				Msg("Task lease expired")
+
+			if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TASK_LEASE_EXPIRED", map[string]any{
+				"task_id":          task.ID,
+				"task_name":        task.TaskName,
+				"claimed_by":       task.ClaimedBy,
+				"lease_expires_at": task.LeaseExpiresAt,
+				"retry_count":      newRetryCount,
+				"status":           status,
+			}); err != nil {
+				return err
+			}
+
+			if status == "FAILED" {
+				return w.failWorkflow(ctx, wf, task)
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to release expired task")
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// mapItems returns the item tasks of a map step, in item order
+func mapItems(step string, tasks []model.Tasks) []model.Tasks {
+	items := []model.Tasks{}
+	for _, t := range tasks {
+		if itemStep, _, isItem := registry.MapItemStep(t.TaskName); isItem && itemStep == step {
+			items = append(items, t)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		_, a, _ := registry.MapItemStep(items[i].TaskName)
+		_, b, _ := registry.MapItemStep(items[j].TaskName)
+		return a < b
+	})
+
+	return items
+}
+
+// runMap executes a map step. The first run creates one task per array
+// element and parks the step in WAITING; once every item has finished the
+// step is woken and completes with the collected results
+func (w *WorkflowWorker) runMap(ctx context.Context, wf *model.WorkflowInstances, task model.Tasks, taskDef *registry.TaskDefinition, retryCount int32, policy registry.RetryPolicy) {
+	var output *string
+
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the instance so items finishing now see the step parked
+		if _, err := w.repo.LockWorkflow(ctx, wf.ID); err != nil {
+			return err
+		}
+
+		tasks, err := w.repo.GetTasksByWorkflowID(ctx, wf.ID)
+		if err != nil {
+			return err
+		}
+
+		items := mapItems(task.TaskName, tasks)
+		if len(items) == 0 {
+			output, err = w.startMapItems(ctx, task, taskDef)
+			return err
+		}
+
+		for _, item := range items {
+			if isTaskActive(item) {
+				return w.parkTask(ctx, task)
+			}
+		}
+
+		output, err = collectMapResults(taskDef.Map, items)
+		return err
+	})
+	if errors.Is(err, errLeaseLost) {
+		logger.Warn().Int64("task_id", task.ID).Msg("Task lease lost before waiting for map items")
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Str("task_name", task.TaskName).Msg("Failed to run map step")
+		w.handleTaskFailure(ctx, task, retryCount, policy, err)
+		return
+	}
+	if output == nil {
+		return
+	}
+
+	task.OutputPayload = output
+	w.handleTaskSuccess(ctx, task, retryCount)
+}
+
+// startMapItems creates the item tasks of a map step, at most Parallelism of
+// them PENDING and the rest WAITING, and parks the step. An empty array needs
+// no items: its (empty) output is returned right away
+func (w *WorkflowWorker) startMapItems(ctx context.Context, task model.Tasks, taskDef *registry.TaskDefinition) (*string, error) {
+	spec := taskDef.Map
+
+	input := map[string]any{}
+	if task.InputPayload != nil {
+		if err := json.Unmarshal([]byte(*task.InputPayload), &input); err != nil {
+			return nil, registry.NonRetryable(fmt.Errorf("decode map input: %w", err))
+		}
+	}
+	elements, ok := input[spec.Field].([]any)
+	if !ok {
+		return nil, registry.NonRetryable(fmt.Errorf("map field %s is not an array", spec.Field))
+	}
+	if len(elements) == 0 {
+		return collectMapResults(spec, nil)
+	}
+
+	parallelism := spec.EffectiveParallelism()
+	for i, element := range elements {
+		elementJSON, err := json.Marshal(element)
+		if err != nil {
+			return nil, registry.NonRetryable(fmt.Errorf("encode map item %d: %w", i, err))
+		}
+		elementStr := string(elementJSON)
+
+		status := model.TasksStatus_Pending
+		if i >= parallelism {
+			status = model.TasksStatus_Waiting
+		}
+
+		if err := w.repo.CreateTask(ctx, &model.Tasks{
+			WorkflowInstanceID: task.WorkflowInstanceID,
+			TaskName:           registry.MapItemTaskName(task.TaskName, i),
+			Status:             &status,
+			InputPayload:       &elementStr,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.parkTask(ctx, task); err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Str("workflow_id", task.WorkflowInstanceID).
+		Str("task_name", task.TaskName).
+		Int("items", len(elements)).
+		Int("parallelism", parallelism).
+		Msg("Map step started")
+
+	return nil, w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "MAP_STARTED", map[string]any{
+		"task_id":     task.ID,
+		"task_name":   task.TaskName,
+		"items":       len(elements),
+		"parallelism": parallelism,
+	})
+}
+
+// collectMapResults builds the output of a map step from its finished items:
+// their outputs in item order, with null for failed items whose indexes are
+// listed in "failed"
+func collectMapResults(spec *registry.MapSpec, items []model.Tasks) (*string, error) {
+	results := make([]any, 0, len(items))
+	failed := []int{}
+
+	for _, item := range items {
+		_, index, _ := registry.MapItemStep(item.TaskName)
+
+		if item.Status == nil || *item.Status != model.TasksStatus_Completed {
+			if spec.FailurePolicy == registry.MapFailFast {
+				return nil, registry.NonRetryable(fmt.Errorf("map item %s did not complete", item.TaskName))
+			}
+			results = append(results, nil)
+			failed = append(failed, index)
+			continue
+		}
+
+		var result any
+		if item.OutputPayload != nil {
+			if err := json.Unmarshal([]byte(*item.OutputPayload), &result); err != nil {
+				return nil, registry.NonRetryable(fmt.Errorf("decode output of %s: %w", item.TaskName, err))
+			}
+		}
+		results = append(results, result)
+	}
+
+	outputJSON, err := json.Marshal(map[string]any{
+		"results": results,
+		"failed":  failed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	outputStr := string(outputJSON)
+
+	return &outputStr, nil
+}
+
+// continuesOnError reports whether a failed task is an item of a map step
+// that tolerates failed items
+func (w *WorkflowWorker) continuesOnError(wf *model.WorkflowInstances, task model.Tasks) bool {
+	if _, _, isItem := registry.MapItemStep(task.TaskName); !isItem {
+		return false
+	}
+
+	def, err := w.definitionFor(wf)
+	if err != nil {
+		return false
+	}
+	taskDef, exists := def.Step(task.TaskName)
+
+	return exists && taskDef.Map.FailurePolicy == registry.MapContinueOnError
+}
+
+// advanceMap runs after an item of a map step finished: it starts waiting
+// items up to the parallelism limit, and wakes the map step once no item is
+// left. It runs inside the caller's transaction with the workflow locked
+func (w *WorkflowWorker) advanceMap(ctx context.Context, wf *model.WorkflowInstances, item model.Tasks) error {
+	step, _, _ := registry.MapItemStep(item.TaskName)
+	def, err := w.definitionFor(wf)
+	if err != nil {
+		return err
+	}
+	taskDef, exists := def.Tasks[step]
+	if !exists || taskDef.Map == nil {
+		return fmt.Errorf("map step not found: %s", step)
+	}
+
+	tasks, err := w.repo.GetTasksByWorkflowID(ctx, wf.ID)
+	if err != nil {
+		return fmt.Errorf("get tasks: %w", err)
+	}
+
+	running := 0
+	waiting := []model.Tasks{}
+	for _, t := range mapItems(step, tasks) {
+		if t.Status != nil && *t.Status == model.TasksStatus_Waiting {
+			waiting = append(waiting, t)
+		} else if isTaskActive(t) {
+			running++
+		}
+	}
+
+	// Keep Parallelism items running
+	for _, t := range waiting {
+		if running >= taskDef.Map.EffectiveParallelism() {
+			break
+		}
+		if _, err := w.repo.WakeTask(ctx, int(t.ID)); err != nil {
+			return err
+		}
+		running++
+	}
+	if running > 0 {
+		return nil
+	}
+
+	// Every item finished: the map step collects the results
+	stepTask, exists := tasksByName(tasks)[step]
+	if !exists {
+		return fmt.Errorf("map step task not found: %s", step)
+	}
+	_, err = w.repo.WakeTask(ctx, int(stepTask.ID))
+
+	return err
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// TaskPanicError is returned for a task function that panicked
+type TaskPanicError struct {
+	Value any
+	Stack string
+}
+
+func (e *TaskPanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// taskErrorMessage formats err for tasks.error_message, keeping the stack
+// trace of panics
+func taskErrorMessage(err error) string {
+	var panicErr *TaskPanicError
+	if errors.As(err, &panicErr) {
+		return panicErr.Error() + "\n\n" + panicErr.Stack
+	}
+
+	return err.Error()
+}
+
+// runTaskFunc calls fn and converts a panic into a *TaskPanicError so one
+// broken task cannot crash the whole process
+func runTaskFunc(ctx context.Context, fn registry.TaskFunc, task *model.Tasks) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &TaskPanicError{
+				Value: r,
+				Stack: string(debug.Stack()),
+			}
+		}
+	}()
+
+	return fn(ctx, task)
+}
+
+// recordPanic writes a TASK_PANICKED activity log. The stack trace reaches
+// tasks.error_message through handleTaskFailure
+func (w *WorkflowWorker) recordPanic(ctx context.Context, task model.Tasks, panicErr *TaskPanicError) {
+	logger.Error().
+		Str("task_name", task.TaskName).
+		Int64("task_id", task.ID).
+		Interface("panic", panicErr.Value).
+		Str("stack", panicErr.Stack).
+		Msg("Task panicked")
+
+	if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TASK_PANICKED", map[string]any{
+		"task_id":   task.ID,
+		"task_name": task.TaskName,
+		"panic":     fmt.Sprint(panicErr.Value),
+		"stack":     panicErr.Stack,
+	}); err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to create task panic activity log")
+	}
+}
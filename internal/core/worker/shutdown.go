@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// errWorkerShutdown cancels tasks still running when the grace period ends
+var errWorkerShutdown = errors.New("worker shutting down")
+
+// Shutdown stops claiming new tasks and waits up to the configured grace
+// period (or until ctx is done) for running tasks to finish. Tasks that are
+// still running afterwards are cancelled, and every claim this worker still
+// holds is released back to PENDING so another worker can pick it up
+func (w *WorkflowWorker) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	// Wait for the poll loop to return: a ClaimTasks call in flight would
+	// otherwise start tasks after running.Wait already saw none
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+	}
+
+	logger.Info().
+		Int("running_tasks", len(w.slots)).
+		Dur("grace_period", w.shutdownGrace).
+		Msg("Worker draining running tasks...")
+
+	drained := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(drained)
+	}()
+
+	grace := time.NewTimer(w.shutdownGrace)
+	defer grace.Stop()
+
+	select {
+	case <-drained:
+		logger.Info().Msg("Worker drained")
+	case <-grace.C:
+		w.cancelRunning(ctx, drained)
+	case <-ctx.Done():
+		w.cancelRunning(ctx, drained)
+	}
+
+	// Give back claims that are still held (cancelled tasks, or claims made
+	// while the poll loop was stopping)
+	released, err := w.repo.ReleaseTaskClaims(context.WithoutCancel(ctx), w.workerID)
+	if err != nil {
+		logger.Error().Err(err).Str("worker_id", w.workerID).Msg("Failed to release task claims")
+		return err
+	}
+
+	if released > 0 {
+		logger.Info().Int64("released_tasks", released).Str("worker_id", w.workerID).Msg("Released unfinished task claims")
+	}
+
+	return nil
+}
+
+// cancelRunning cancels the tasks still running after the grace period and
+// waits for them to return
+func (w *WorkflowWorker) cancelRunning(ctx context.Context, drained <-chan struct{}) {
+	logger.Warn().Int("running_tasks", len(w.slots)).Msg("Grace period over, cancelling running tasks")
+	w.cancelTasks(errWorkerShutdown)
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// waitForSignal runs a signal step. If the signal was already sent the step
+// completes with its payload; otherwise the task is parked in WAITING (or
+// deferred until its timeout) and requeued when the signal arrives. A step
+// whose timeout passed routes to its fallback
+func (w *WorkflowWorker) waitForSignal(ctx context.Context, task model.Tasks, taskDef *registry.TaskDefinition, retryCount int32) {
+	spec := taskDef.Signal
+	var signal *model.WorkflowSignals
+	timedOut := false
+
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the instance so a signal sent now is either seen here or resumes the task
+		if _, err := w.repo.LockWorkflow(ctx, task.WorkflowInstanceID); err != nil {
+			return err
+		}
+
+		found, err := w.repo.GetSignalForTask(ctx, task.WorkflowInstanceID, spec.Name, task.ID)
+		if err != nil {
+			return err
+		}
+		if found != nil {
+			signal = found
+			if found.ConsumedByTaskID != nil {
+				return nil
+			}
+			return w.repo.ConsumeSignal(ctx, found.ID, task.ID)
+		}
+
+		if spec.Timeout <= 0 {
+			return w.parkTask(ctx, task)
+		}
+
+		// wait_until is set the first time the step waits and never moved after
+		deadline := time.Now().Add(spec.Timeout)
+		if task.WaitUntil != nil {
+			deadline = *task.WaitUntil
+		}
+		if !time.Now().Before(deadline) {
+			timedOut = true
+			return nil
+		}
+
+		deferred, err := w.repo.DeferTask(ctx, int(task.ID), w.workerID, deadline)
+		if err != nil {
+			return err
+		}
+		if !deferred {
+			return errLeaseLost
+		}
+		return nil
+	})
+	if errors.Is(err, errLeaseLost) {
+		logger.Warn().Int64("task_id", task.ID).Msg("Task lease lost before waiting for signal")
+		return
+	}
+	if err != nil {
+		// A retry would reschedule the task and look like a timeout;
+		// leave it to the lease reaper instead
+		logger.Error().Err(err).Int64("task_id", task.ID).Str("signal_name", spec.Name).Msg("Failed to wait for signal")
+		return
+	}
+
+	if signal != nil {
+		logger.Info().
+			Str("task_name", task.TaskName).
+			Str("workflow_id", task.WorkflowInstanceID).
+			Str("signal_name", spec.Name).
+			Msg("Signal received")
+
+		task.OutputPayload = signal.Payload
+		w.handleTaskSuccess(ctx, task, retryCount)
+		return
+	}
+	if !timedOut {
+		return
+	}
+
+	logger.Warn().
+		Str("task_name", task.TaskName).
+		Str("workflow_id", task.WorkflowInstanceID).
+		Str("signal_name", spec.Name).
+		Str("fallback", spec.Fallback).
+		Msg("Signal wait timed out")
+
+	if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "SIGNAL_TIMED_OUT", map[string]any{
+		"task_id":     task.ID,
+		"task_name":   task.TaskName,
+		"signal_name": spec.Name,
+		"timeout":     spec.Timeout.String(),
+		"fallback":    spec.Fallback,
+	}); err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to create signal timeout activity log")
+	}
+
+	task.OutputPayload = task.InputPayload
+	w.completeTask(ctx, task, retryCount, spec.Fallback)
+}
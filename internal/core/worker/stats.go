@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WorkerStats is a snapshot of the worker pool's slot utilisation
+type WorkerStats struct {
+	WorkerID       string  `json:"worker_id"`
+	MaxConcurrency int     `json:"max_concurrency"`
+	BusySlots      int     `json:"busy_slots"`
+	FreeSlots      int     `json:"free_slots"`
+	Utilisation    float64 `json:"utilisation"`
+	// AvgUtilisation is the share of slot time spent on finished tasks since start
+	AvgUtilisation float64 `json:"avg_utilisation"`
+	TasksStarted   int64   `json:"tasks_started"`
+	TasksFinished  int64   `json:"tasks_finished"`
+	UptimeSeconds  float64 `json:"uptime_seconds"`
+}
+
+// poolStats collects counters updated concurrently by task goroutines
+type poolStats struct {
+	startedAt     atomic.Int64 // unix nanos
+	tasksStarted  atomic.Int64
+	tasksFinished atomic.Int64
+	busyNanos     atomic.Int64
+}
+
+func (s *poolStats) start(now time.Time) {
+	s.startedAt.Store(now.UnixNano())
+}
+
+func (s *poolStats) taskStarted() {
+	s.tasksStarted.Add(1)
+}
+
+func (s *poolStats) taskFinished(elapsed time.Duration) {
+	s.tasksFinished.Add(1)
+	s.busyNanos.Add(int64(elapsed))
+}
+
+// Stats returns the current slot utilisation of the worker pool
+func (w *WorkflowWorker) Stats() WorkerStats {
+	busy := len(w.slots)
+
+	stats := WorkerStats{
+		WorkerID:       w.workerID,
+		MaxConcurrency: w.maxConcurrency,
+		BusySlots:      busy,
+		FreeSlots:      w.maxConcurrency - busy,
+		Utilisation:    float64(busy) / float64(w.maxConcurrency),
+		TasksStarted:   w.stats.tasksStarted.Load(),
+		TasksFinished:  w.stats.tasksFinished.Load(),
+	}
+
+	if startedAt := w.stats.startedAt.Load(); startedAt > 0 {
+		uptime := time.Since(time.Unix(0, startedAt))
+		stats.UptimeSeconds = uptime.Seconds()
+		if uptime > 0 {
+			stats.AvgUtilisation = float64(w.stats.busyNanos.Load()) / (float64(uptime) * float64(w.maxConcurrency))
+		}
+	}
+
+	return stats
+}
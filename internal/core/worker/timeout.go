@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// isWorkflowActive reports whether the workflow can still schedule tasks
+func isWorkflowActive(wf *model.WorkflowInstances) bool {
+	return wf.Status == nil ||
+		*wf.Status == model.WorkflowInstancesStatus_Scheduled ||
+		*wf.Status == model.WorkflowInstancesStatus_Pending ||
+		*wf.Status == model.WorkflowInstancesStatus_Running
+}
+
+// deadlinePassed reports whether the workflow's overall deadline is over
+func deadlinePassed(wf *model.WorkflowInstances) bool {
+	return wf.DeadlineAt != nil && time.Now().After(*wf.DeadlineAt)
+}
+
+// handleTimeout times out the workflow in its own transaction
+func (w *WorkflowWorker) handleTimeout(ctx context.Context, wf *model.WorkflowInstances, task *model.Tasks) {
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		return w.timeOutWorkflow(ctx, wf, task)
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("workflow_id", wf.ID).Msg("Failed to time out workflow")
+	}
+}
+
+// timeOutWorkflow fails a workflow that exceeded its deadline, along with the
+// task being processed (if any) and every task still waiting to run. Completed
+// steps with a compensation are undone first, like after a failed task
+func (w *WorkflowWorker) timeOutWorkflow(ctx context.Context, wf *model.WorkflowInstances, task *model.Tasks) error {
+	wf, err := w.repo.LockWorkflow(ctx, wf.ID)
+	if err != nil {
+		return err
+	}
+
+	if task != nil {
+		// Only the task this worker still holds; a reaped one is failed below
+		// with the other pending tasks
+		if _, err := w.repo.FailTask(ctx, int(task.ID), w.workerID); err != nil {
+			return err
+		}
+	}
+
+	if !isWorkflowActive(wf) {
+		// Already finished or timed out by another worker
+		return nil
+	}
+
+	if err := w.cancelChildren(ctx, wf); err != nil {
+		return err
+	}
+
+	status := "compensating"
+	compensating, err := w.startCompensation(ctx, wf, task, "timeout")
+	if err != nil {
+		return err
+	}
+	if !compensating {
+		status = "failed"
+		if err := w.repo.UpdatePendingTasksStatus(ctx, wf.ID, "FAILED"); err != nil {
+			return err
+		}
+		if err := w.endWorkflow(ctx, wf, "FAILED"); err != nil {
+			return err
+		}
+	}
+
+	logger.Warn().
+		Str("workflow_id", wf.ID).
+		Str("workflow_name", wf.WorkflowName).
+		Time("deadline_at", *wf.DeadlineAt).
+		Msg("Workflow TIMED OUT")
+
+	details := map[string]any{
+		"workflow_id":   wf.ID,
+		"workflow_name": wf.WorkflowName,
+		"deadline_at":   wf.DeadlineAt.Format(time.RFC3339),
+		"reason":        "timeout",
+		"status":        status,
+	}
+	var taskName *string
+	if task != nil {
+		taskName = &task.TaskName
+		details["task_id"] = task.ID
+	}
+
+	return w.logActivity(ctx, wf.ID, taskName, "WORKFLOW_TIMED_OUT", details)
+}
+
+// reapTimedOutWorkflows fails workflows whose deadline passed while none of
+// their tasks was running (e.g. waiting for a retry)
+func (w *WorkflowWorker) reapTimedOutWorkflows(ctx context.Context) {
+	workflows, err := w.repo.GetTimedOutWorkflows(ctx, w.batchSize)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error fetching timed out workflows")
+		return
+	}
+
+	for i := range workflows {
+		w.handleTimeout(ctx, &workflows[i], nil)
+	}
+}
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// fireTimer completes a timer step, passing its input through as output.
+// The wait itself happens in the queue: the task is created with
+// scheduled_at set to the fire time and is not claimed before
+func (w *WorkflowWorker) fireTimer(ctx context.Context, task model.Tasks, taskDef *registry.TaskDefinition, retryCount int32, policy registry.RetryPolicy) {
+	if task.ScheduledAt == nil {
+		// The fire time could not be read when the step was reached
+		_, err := taskDef.Timer.FireAt(task.InputPayload, time.Now())
+		if err == nil {
+			err = errors.New("timer has no fire time")
+		}
+		w.handleTaskFailure(ctx, task, retryCount, policy, registry.NonRetryable(err))
+		return
+	}
+
+	logger.Info().
+		Str("task_name", task.TaskName).
+		Str("workflow_id", task.WorkflowInstanceID).
+		Time("scheduled_at", *task.ScheduledAt).
+		Msg("Timer fired")
+
+	if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TIMER_FIRED", map[string]any{
+		"task_id":      task.ID,
+		"task_name":    task.TaskName,
+		"scheduled_at": task.ScheduledAt.Format(time.RFC3339),
+	}); err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to create timer fired activity log")
+	}
+
+	task.OutputPayload = task.InputPayload
+	w.handleTaskSuccess(ctx, task, retryCount)
+}
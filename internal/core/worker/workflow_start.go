@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// startWorkflow moves a workflow to RUNNING when its first task is claimed:
+// from PENDING, or from SCHEDULED for one created with start_at/delay (its
+// root tasks are only claimed once the start time has passed)
+func (w *WorkflowWorker) startWorkflow(ctx context.Context, wf *model.WorkflowInstances) {
+	if wf.Status == nil ||
+		(*wf.Status != model.WorkflowInstancesStatus_Pending && *wf.Status != model.WorkflowInstancesStatus_Scheduled) {
+		return
+	}
+	delayed := *wf.Status == model.WorkflowInstancesStatus_Scheduled
+
+	started, err := w.repo.TransitionWorkflowStatus(ctx, wf.ID, "RUNNING", "PENDING", "SCHEDULED")
+	if err != nil {
+		logger.Error().Err(err).Str("workflow_id", wf.ID).Msg("Failed to start workflow")
+		return
+	}
+
+	if !started {
+		// Another root task of the same instance got there first, or it was cancelled
+		return
+	}
+	status := model.WorkflowInstancesStatus_Running
+	wf.Status = &status
+
+	logger.Info().
+		Str("workflow_id", wf.ID).
+		Str("workflow_name", wf.WorkflowName).
+		Bool("delayed", delayed).
+		Msg("Workflow started")
+
+	if err := w.logActivity(ctx, wf.ID, nil, "WORKFLOW_STARTED", map[string]any{
+		"workflow_id":   wf.ID,
+		"workflow_name": wf.WorkflowName,
+		"delayed":       delayed,
+	}); err != nil {
+		logger.Error().Err(err).Str("workflow_id", wf.ID).Msg("Failed to create workflow started activity log")
+	}
+}
@@ -2,8 +2,8 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -15,51 +15,159 @@ import (
 )
 
 type WorkflowWorker struct {
-	repo         port.WorkflowRepository
-	registry     *registry.WorkflowRegistry
-	pollInterval time.Duration
-	batchSize    int
-	taskTimeout  time.Duration
-	maxRetries   int
+	repo              port.WorkflowRepository
+	registry          *registry.WorkflowRegistry
+	svc               port.WorkflowService
+	workerID          string
+	pollInterval      time.Duration
+	batchSize         int
+	maxConcurrency    int
+	taskTimeout       time.Duration
+	maxRetries        int
+	leaseDuration     time.Duration
+	heartbeatInterval time.Duration
+	reaperInterval    time.Duration
+	shutdownGrace     time.Duration
+
+	// slots holds one token per running task; wake triggers an immediate
+	// poll when a slot frees up
+	slots chan struct{}
+	wake  chan struct{}
+	stats poolStats
+
+	// running tasks use taskCtx, which outlives the poll loop so Shutdown can
+	// let them drain; stop ends the poll loop and done is closed once it has
+	// returned, so no claim can start after Shutdown waits for running tasks
+	running     sync.WaitGroup
+	taskCtx     context.Context
+	cancelTasks context.CancelCauseFunc
+	stop        chan struct{}
+	stopOnce    sync.Once
+	done        chan struct{}
 }
 
-func NewWorkflowWorker(repo port.WorkflowRepository, reg *registry.WorkflowRegistry, cfg *config.WorkerConfig) *WorkflowWorker {
+// NewWorkflowWorker creates a worker, rejecting a configuration it could not
+// run with (e.g. a heartbeat that would let its own leases expire)
+func NewWorkflowWorker(repo port.WorkflowRepository, reg *registry.WorkflowRegistry, svc port.WorkflowService, cfg *config.WorkerConfig) (*WorkflowWorker, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	taskCtx, cancelTasks := context.WithCancelCause(context.Background())
+
 	return &WorkflowWorker{
-		repo:         repo,
-		registry:     reg,
-		pollInterval: cfg.PollInterval,
-		batchSize:    cfg.BatchSize,
-		taskTimeout:  cfg.TaskTimeout,
-		maxRetries:   cfg.MaxRetries,
+		repo:              repo,
+		registry:          reg,
+		svc:               svc,
+		workerID:          cfg.WorkerID,
+		pollInterval:      cfg.PollInterval,
+		batchSize:         cfg.BatchSize,
+		maxConcurrency:    cfg.MaxConcurrency,
+		taskTimeout:       cfg.TaskTimeout,
+		maxRetries:        cfg.MaxRetries,
+		leaseDuration:     cfg.LeaseDuration,
+		heartbeatInterval: cfg.HeartbeatInterval,
+		reaperInterval:    cfg.ReaperInterval,
+		shutdownGrace:     cfg.ShutdownGrace,
+		slots:             make(chan struct{}, cfg.MaxConcurrency),
+		wake:              make(chan struct{}, 1),
+		taskCtx:           taskCtx,
+		cancelTasks:       cancelTasks,
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
+	}, nil
+}
+
+// validateConfig checks the intervals the worker's tickers and leases rely on
+func validateConfig(cfg *config.WorkerConfig) error {
+	if cfg.WorkerID == "" {
+		return errors.New("invalid worker config: worker ID cannot be empty")
+	}
+	if cfg.BatchSize <= 0 {
+		return fmt.Errorf("invalid worker config: batch size must be positive, got %d", cfg.BatchSize)
 	}
+	if cfg.MaxConcurrency <= 0 {
+		return fmt.Errorf("invalid worker config: max concurrency must be positive, got %d", cfg.MaxConcurrency)
+	}
+
+	positive := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"poll interval", cfg.PollInterval},
+		{"task timeout", cfg.TaskTimeout},
+		{"lease duration", cfg.LeaseDuration},
+		{"heartbeat interval", cfg.HeartbeatInterval},
+		{"reaper interval", cfg.ReaperInterval},
+	}
+	for _, d := range positive {
+		if d.value <= 0 {
+			return fmt.Errorf("invalid worker config: %s must be positive, got %s", d.name, d.value)
+		}
+	}
+
+	// A lease must be renewed before it runs out
+	if cfg.HeartbeatInterval >= cfg.LeaseDuration {
+		return fmt.Errorf("invalid worker config: heartbeat interval (%s) must be shorter than lease duration (%s)",
+			cfg.HeartbeatInterval, cfg.LeaseDuration)
+	}
+	if cfg.ShutdownGrace < 0 {
+		return fmt.Errorf("invalid worker config: shutdown grace cannot be negative, got %s", cfg.ShutdownGrace)
+	}
+
+	return nil
 }
 
 func (w *WorkflowWorker) Start(ctx context.Context) {
+	defer close(w.done)
+
 	ticker := time.NewTicker(w.pollInterval)
 	defer ticker.Stop()
 
+	w.stats.start(time.Now())
+
 	logger.Info().
+		Str("worker_id", w.workerID).
 		Dur("poll_interval", w.pollInterval).
 		Int("batch_size", w.batchSize).
+		Int("max_concurrency", w.maxConcurrency).
 		Dur("task_timeout", w.taskTimeout).
+		Dur("lease_duration", w.leaseDuration).
 		Msg("Worker started: Waiting for jobs...")
 
+	// คืนงานที่ lease หมดอายุ (worker ตายระหว่างทำงาน) กลับเข้าคิว
+	go w.runReaper(ctx)
+
 	for {
 		select {
 		case <-ctx.Done(): // สั้งปิด Work
 			logger.Info().Msg("Worker stopping...")
 			return
+		case <-w.stop: // Shutdown: หยุดรับงานใหม่
+			logger.Info().Msg("Worker stopped claiming new tasks")
+			return
 		case <-ticker.C:
-			w.processBatch(ctx)
+		case <-w.wake: // มี slot ว่าง ดึงงานต่อทันที
 		}
+
+		w.fillSlots(ctx)
 	}
 }
 
-func (w *WorkflowWorker) processBatch(ctx context.Context) {
-	// 	1. ดึงงาน PENDING ตาม batch size ที่กำหนด
-	tasks, err := w.repo.GetTaskPending(ctx, w.batchSize)
+// fillSlots claims as many tasks as there are free slots (at most batchSize)
+// and starts each one in its own goroutine
+func (w *WorkflowWorker) fillSlots(ctx context.Context) {
+	free := w.maxConcurrency - len(w.slots)
+	if free <= 0 {
+		return // ทุก slot ไม่ว่าง รอให้งานเสร็จก่อน
+	}
+
+	limit := min(free, w.batchSize)
+
+	// 	1. จองงาน PENDING ตามจำนวน slot ที่ว่าง (กัน worker ตัวอื่นหยิบงานซ้ำ)
+	tasks, err := w.repo.ClaimTasks(ctx, w.workerID, limit, w.leaseDuration)
 	if err != nil {
-		logger.Error().Err(err).Msg("Error fetching tasks")
+		logger.Error().Err(err).Msg("Error claiming tasks")
 		return
 	}
 
@@ -67,21 +175,51 @@ func (w *WorkflowWorker) processBatch(ctx context.Context) {
 		return // ไม่มีงานก็ให้นอนต่อ
 	}
 
-	logger.Info().Int("count", len(tasks)).Msg("Found pending jobs! Processing...")
+	logger.Info().
+		Int("count", len(tasks)).
+		Str("worker_id", w.workerID).
+		Int("busy_slots", len(w.slots)+len(tasks)).
+		Int("max_concurrency", w.maxConcurrency).
+		Msg("Claimed pending jobs! Processing...")
 
-	// 2. รันงาน (Concurrency!) with WaitGroup
-	var wg sync.WaitGroup
+	// 2. รันงานใน slot ของตัวเอง ไม่ต้องรอกันทั้ง batch
 	for _, task := range tasks {
-		wg.Add(1)
-		// ส่ง job เข้า Go Routine แยก เพื่อให้ทำพร้อมกันได้
+		w.slots <- struct{}{}
+		w.stats.taskStarted()
+		w.running.Add(1)
+
 		go func(t model.Tasks) {
-			defer wg.Done()
-			w.executeTask(ctx, t)
+			startedAt := time.Now()
+			defer w.running.Done()
+			defer w.releaseSlot(startedAt)
+			defer func() {
+				// Last line of defence: never let one task take the process down
+				if r := recover(); r != nil {
+					logger.Error().Interface("panic", r).Int64("task_id", t.ID).Msg("Recovered panic while processing task")
+				}
+			}()
+			w.executeTask(w.taskCtx, t)
 		}(task)
 	}
 
-	// รอให้ทุก goroutine ทำงานเสร็จก่อน return
-	wg.Wait()
+	// Got a full batch - there may be more work waiting
+	if len(tasks) == limit {
+		w.signalWake()
+	}
+}
+
+// releaseSlot frees a slot and wakes the poll loop to refill it
+func (w *WorkflowWorker) releaseSlot(startedAt time.Time) {
+	w.stats.taskFinished(time.Since(startedAt))
+	<-w.slots
+	w.signalWake()
+}
+
+func (w *WorkflowWorker) signalWake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
 }
 
 func (w *WorkflowWorker) executeTask(ctx context.Context, task model.Tasks) {
@@ -98,35 +236,29 @@ func (w *WorkflowWorker) executeTask(ctx context.Context, task model.Tasks) {
 		Int32("retry_count", retryCount).
 		Msg("Executing task")
 
-	// Update status to IN_PROGRESS or RETRYING
-	status := "IN_PROGRESS"
+	// Keep the claim alive while this worker owns the task
+	ctx, stopHeartbeat := w.startHeartbeat(ctx, task)
+	defer stopHeartbeat()
+
+	// Task is already IN_PROGRESS from ClaimTasks; mark retries as RETRYING
 	if retryCount > 0 {
-		status = "RETRYING"
+		held, err := w.repo.MarkTaskRetrying(ctx, int(task.ID), w.workerID)
+		if err != nil {
+			logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to mark task as retrying")
+		} else if !held {
+			logger.Warn().Int64("task_id", task.ID).Str("worker_id", w.workerID).Msg("Task lease lost before execution")
+			return
+		}
 	}
-	w.repo.UpdateTaskStatus(ctx, int(task.ID), status)
 
 	// Log task start
-	eventType := "TASK_STARTED"
-	detailsMap := map[string]interface{}{
+	if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TASK_STARTED", map[string]any{
 		"task_id":     task.ID,
 		"task_name":   task.TaskName,
 		"workflow_id": task.WorkflowInstanceID,
 		"retry_count": retryCount,
-	}
-	detailsJSON, err := json.Marshal(detailsMap)
-	if err != nil {
-		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to marshal task start details")
-		return
-	}
-
-	details := string(detailsJSON)
-	err = w.repo.CreateActivityLog(ctx, &model.ActivityLogs{
-		WorkflowInstanceID: task.WorkflowInstanceID,
-		TaskName:           &task.TaskName,
-		EventType:          &eventType,
-		Details:            &details,
-	})
-	if err != nil {
+		"worker_id":   w.workerID,
+	}); err != nil {
 		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to create task start activity log")
 	}
 
@@ -134,30 +266,104 @@ func (w *WorkflowWorker) executeTask(ctx context.Context, task model.Tasks) {
 	wf, err := w.repo.GetWorkflowByID(ctx, task.WorkflowInstanceID)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to get workflow")
-		w.handleTaskFailure(ctx, task, retryCount, err)
+		w.handleTaskFailure(ctx, task, retryCount, w.defaultRetryPolicy(), err)
+		return
+	}
+	// The definition version the instance started with, not the latest one
+	def, err := w.definitionFor(wf)
+	if err != nil {
+		logger.Error().Err(err).Str("workflow_id", wf.ID).Msg("Workflow version not registered")
+		w.handleTaskFailure(ctx, task, retryCount, w.defaultRetryPolicy(), err)
+		return
+	}
+	policy := w.retryPolicy(def, task.TaskName)
+
+	// Workflow already finished (e.g. timed out) - don't run stale tasks
+	if !canRunTask(wf, task) {
+		w.skipTask(ctx, task)
+		return
+	}
+	w.startWorkflow(ctx, wf)
+
+	// Compensations still run after the deadline has passed
+	_, isCompensation := registry.CompensatedTaskName(task.TaskName)
+	if !isCompensation && deadlinePassed(wf) {
+		w.handleTimeout(ctx, wf, &task)
 		return
 	}
 
+	if taskDef, exists := def.Tasks[task.TaskName]; exists {
+		// Child workflow step: start the child or collect its result
+		if taskDef.ChildWorkflow != "" {
+			w.runChildWorkflow(ctx, wf, task, taskDef, retryCount, policy)
+			return
+		}
+		// Timer step: only claimed once its fire time has passed
+		if taskDef.Timer != nil {
+			w.fireTimer(ctx, task, taskDef, retryCount, policy)
+			return
+		}
+		// Map step: fan out over the input array, then collect the results
+		if taskDef.Map != nil {
+			w.runMap(ctx, wf, task, taskDef, retryCount, policy)
+			return
+		}
+		// Signal step: completes once the signal was sent to the instance
+		if taskDef.Signal != nil {
+			w.waitForSignal(ctx, task, taskDef, retryCount)
+			return
+		}
+	}
+
 	// ดึง task function จาก registry
-	taskFunc, exists := w.registry.GetTaskFunc(wf.WorkflowName, task.TaskName)
+	taskFunc, exists := resolveTaskFunc(def, task.TaskName)
 	if !exists {
 		err := errors.New("task function not found: " + task.TaskName)
 		logger.Error().Err(err).Str("task_name", task.TaskName).Msg("No task function registered")
-		w.handleTaskFailure(ctx, task, retryCount, err)
+		w.handleTaskFailure(ctx, task, retryCount, policy, err)
 		return
 	}
 
-	// Execute with timeout
-	execCtx, cancel := context.WithTimeout(ctx, w.taskTimeout)
+	// Execute with the task's start-to-close timeout, bounded by the workflow deadline
+	timeout := w.taskTimeout
+	if taskDef, exists := def.Step(task.TaskName); exists && taskDef.Timeout > 0 {
+		timeout = taskDef.Timeout
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	if wf.DeadlineAt != nil && !isCompensation {
+		var cancelDeadline context.CancelFunc
+		execCtx, cancelDeadline = context.WithDeadline(execCtx, *wf.DeadlineAt)
+		defer cancelDeadline()
+	}
 
-	err = taskFunc(execCtx, &task)
+	// Tasks can read the workflow input and earlier outputs (registry.StateFromContext)
+	if state, err := registry.ParseWorkflowState(wf.State, wf.CurrentInput); err == nil {
+		execCtx = registry.WithState(execCtx, state)
+	} else {
+		logger.Warn().Err(err).Str("workflow_id", wf.ID).Msg("Ignoring unreadable workflow state")
+	}
+
+	err = runTaskFunc(execCtx, taskFunc, &task)
+	if cause := context.Cause(ctx); errors.Is(cause, errLeaseLost) || errors.Is(cause, errWorkerShutdown) {
+		// The claim was taken away or is being released; don't touch task state
+		logger.Warn().Err(cause).Int64("task_id", task.ID).Msg("Discarding task result")
+		return
+	}
 	if err != nil {
 		logger.Error().Err(err).
 			Str("task_name", task.TaskName).
 			Int64("task_id", task.ID).
 			Msg("Task execution failed")
-		w.handleTaskFailure(ctx, task, retryCount, err)
+		var panicErr *TaskPanicError
+		if errors.As(err, &panicErr) {
+			w.recordPanic(ctx, task, panicErr)
+		}
+		if !isCompensation && deadlinePassed(wf) {
+			w.handleTimeout(ctx, wf, &task)
+			return
+		}
+		w.handleTaskFailure(ctx, task, retryCount, policy, err)
 		return
 	}
 
@@ -165,189 +371,377 @@ func (w *WorkflowWorker) executeTask(ctx context.Context, task model.Tasks) {
 	w.handleTaskSuccess(ctx, task, retryCount)
 }
 
-func (w *WorkflowWorker) orchestrateNextStep(ctx context.Context, currentTask model.Tasks) {
-	// 1. ไปดึงชื่อ Workflow มาก่อน (ต้อง Query join หรือดึงแยก)
-	wf, err := w.repo.GetWorkflowByID(ctx, currentTask.WorkflowInstanceID)
+// orchestrateNextStep creates every task whose dependencies are now all
+// completed, or completes the workflow once no task is left to run. It runs
+// inside the caller's transaction with the workflow row locked, so parallel
+// branches finishing together cannot both (or neither) start a join step; a
+// returned error rolls the whole step transition back. A non-empty route
+// (a task name or registry.End) replaces the task's regular next steps
+func (w *WorkflowWorker) orchestrateNextStep(ctx context.Context, wf *model.WorkflowInstances, currentTask model.Tasks, route string) error {
+	// Compensation (or a step still running when it started) finished
+	if isCompensating(wf) {
+		return w.continueCompensation(ctx, wf)
+	}
+
+	def, err := w.definitionFor(wf)
 	if err != nil {
-		logger.Error().Err(err).Str("workflow_id", currentTask.WorkflowInstanceID).Msg("Failed to get workflow")
-		return
+		return err
 	}
 
-	def, exists := w.registry.GetDefinition(wf.WorkflowName)
-	if !exists {
-		logger.Error().Str("workflow_name", wf.WorkflowName).Msg("Workflow definition not found")
-		return
+	// 1. เลยเวลาของ workflow แล้ว ไม่ต้องสร้าง step ถัดไป
+	if !isWorkflowActive(wf) {
+		return nil
+	}
+	if deadlinePassed(wf) {
+		return w.timeOutWorkflow(ctx, wf, nil)
+	}
+
+	// Items of a map step only move their map step forward
+	if _, _, isItem := registry.MapItemStep(currentTask.TaskName); isItem {
+		return w.advanceMap(ctx, wf, currentTask)
 	}
 
-	// 3. หาว่าเราอยู่ Step ไหน
-	currentStepIndex := -1
-	for i, name := range def.TaskNames {
-		if name == currentTask.TaskName {
-			currentStepIndex = i
-			break
+	tasks, err := w.repo.GetTasksByWorkflowID(ctx, wf.ID)
+	if err != nil {
+		return fmt.Errorf("get tasks: %w", err)
+	}
+	byName := tasksByName(tasks)
+
+	// 2. เลือก task ถัดไป: dependent ปกติ + route target ที่ถูกเลือก
+	currentDef := def.Tasks[currentTask.TaskName]
+	candidates := []string{}
+	routeTarget := route
+	if route == "" {
+		for _, nextTaskName := range def.NextTasks(currentTask.TaskName) {
+			// Route targets (branch cases, signal fallback) only start when chosen
+			if !currentDef.IsRouteTarget(nextTaskName) {
+				candidates = append(candidates, nextTaskName)
+			}
 		}
+
+		if len(currentDef.Branches) > 0 {
+			routeTarget, err = w.takeBranch(ctx, currentTask, currentDef)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if routeTarget == registry.End {
+		routeTarget = ""
 	}
+	if routeTarget != "" {
+		candidates = append(candidates, routeTarget)
+	}
+
+	// 3. สร้าง task ที่ dependency ครบแล้ว (join step รอ parent ทุกตัว)
+	created := 0
+	for _, nextTaskName := range candidates {
+		if _, exists := byName[nextTaskName]; exists {
+			continue
+		}
+
+		var input *string
+		if nextTaskName == routeTarget {
+			// The chosen path starts right away with the routing task's output
+			input = byName[currentTask.TaskName].OutputPayload
+		} else {
+			deps := def.Tasks[nextTaskName].DependsOn
+			if !dependenciesCompleted(byName, deps) {
+				logger.Debug().Str("next_task", nextTaskName).Str("workflow_id", wf.ID).Msg("Waiting for other dependencies")
+				continue
+			}
+
+			input, err = mergeOutputs(byName, deps)
+			if err != nil {
+				return err
+			}
+		}
 
-	// 4. ตัดสินใจ
-	if currentStepIndex != -1 && currentStepIndex < len(def.TaskNames)-1 {
-		// 👉 มี Step ถัดไป! สร้าง Task ใหม่รอไว้เลย
 		status := model.TasksStatus_Pending
-		nextTaskName := def.TaskNames[currentStepIndex+1]
-		logger.Info().Str("next_task", nextTaskName).Str("workflow_id", currentTask.WorkflowInstanceID).Msg("Moving to next step")
+		logger.Info().Str("next_task", nextTaskName).Str("workflow_id", wf.ID).Msg("Moving to next step")
 
 		newTask := &model.Tasks{
-			WorkflowInstanceID: currentTask.WorkflowInstanceID,
+			WorkflowInstanceID: wf.ID,
 			TaskName:           nextTaskName,
 			Status:             &status,
-			InputPayload:       currentTask.OutputPayload,
+			InputPayload:       input,
+			// Timers are parked in the queue until they fire
+			ScheduledAt: def.Tasks[nextTaskName].ScheduledAt(input, time.Now()),
 		}
 
 		if err := w.repo.CreateTask(ctx, newTask); err != nil {
-			logger.Error().Err(err).
-				Str("next_task", nextTaskName).
-				Str("workflow_id", currentTask.WorkflowInstanceID).
-				Msg("Failed to create next task")
-			return
-		}
-	} else {
-		// 🏁 ไม่มี Step ถัดไปแล้ว -> จบงานใหญ่!
-		logger.Info().Str("workflow_name", wf.WorkflowName).Str("workflow_id", wf.ID).Msg("Workflow COMPLETED!")
-		w.repo.UpdateWorkflowStatus(ctx, wf.ID, "COMPLETED")
-
-		// Log workflow completion
-		eventType := "WORKFLOW_COMPLETED"
-		detailsMap := map[string]interface{}{
-			"workflow_id":   wf.ID,
-			"workflow_name": wf.WorkflowName,
-			"total_tasks":   len(def.TaskNames),
-			"status":        "completed",
+			if errors.Is(err, port.ErrTaskAlreadyExists) {
+				// Step was already created (e.g. task re-ran after a lost claim)
+				logger.Warn().Str("next_task", nextTaskName).Str("workflow_id", wf.ID).Msg("Next task already exists")
+				continue
+			}
+			return fmt.Errorf("create next task %s: %w", nextTaskName, err)
 		}
-		detailsJSON, err := json.Marshal(detailsMap)
-		if err != nil {
-			logger.Error().Err(err).Str("workflow_id", wf.ID).Msg("Failed to marshal workflow completion details")
-			return
+		created++
+	}
+	if created > 0 {
+		return nil
+	}
+
+	// 4. Path อื่นยังทำงานอยู่ ให้ task สุดท้ายเป็นคนปิด workflow
+	for _, t := range tasks {
+		if isTaskActive(t) {
+			return nil
 		}
-		details := string(detailsJSON)
-		if err := w.repo.CreateActivityLog(ctx, &model.ActivityLogs{
-			WorkflowInstanceID: wf.ID,
-			TaskName:           nil,
-			EventType:          &eventType,
-			Details:            &details,
-		}); err != nil {
-			logger.Error().Err(err).Str("workflow_id", wf.ID).Msg("Failed to create workflow completion activity log")
+	}
+
+	// 🏁 ไม่มี Step ถัดไปแล้ว -> จบงานใหญ่!
+	logger.Info().Str("workflow_name", wf.WorkflowName).Str("workflow_id", wf.ID).Msg("Workflow COMPLETED!")
+
+	// Output ของ task สุดท้ายของแต่ละ path คือผลลัพธ์ของ workflow
+	leaves := outputTasks(def, tasks)
+	output, err := mergeOutputs(byName, leaves)
+	if err != nil {
+		return err
+	}
+	if output != nil {
+		if err := w.repo.UpdateWorkflowOutput(ctx, wf.ID, *output); err != nil {
+			return fmt.Errorf("save workflow output: %w", err)
 		}
 	}
+	if err := w.endWorkflow(ctx, wf, "COMPLETED"); err != nil {
+		return fmt.Errorf("complete workflow: %w", err)
+	}
+
+	// Log workflow completion
+	return w.logActivity(ctx, wf.ID, nil, "WORKFLOW_COMPLETED", map[string]any{
+		"workflow_id":   wf.ID,
+		"workflow_name": wf.WorkflowName,
+		"total_tasks":   len(tasks),
+		"status":        "completed",
+	})
+}
+
+// defaultRetryPolicy is used for tasks registered without a retry policy:
+// WorkerConfig.MaxRetries retries with 2^n seconds backoff
+func (w *WorkflowWorker) defaultRetryPolicy() registry.RetryPolicy {
+	return registry.RetryPolicy{
+		MaxAttempts:        w.maxRetries + 1,
+		InitialInterval:    2 * time.Second,
+		BackoffCoefficient: 2,
+	}
+}
+
+// definitionFor returns the definition version an instance was started with
+func (w *WorkflowWorker) definitionFor(wf *model.WorkflowInstances) (*registry.WorkflowDefinition, error) {
+	def, exists := w.registry.GetDefinitionVersion(wf.WorkflowName, int(wf.WorkflowVersion))
+	if !exists {
+		return nil, fmt.Errorf("workflow definition not found: %s v%d", wf.WorkflowName, wf.WorkflowVersion)
+	}
+
+	return def, nil
+}
+
+// retryPolicy resolves the effective retry policy of a task
+func (w *WorkflowWorker) retryPolicy(def *registry.WorkflowDefinition, taskName string) registry.RetryPolicy {
+	// Compensations retry like the step they undo
+	if original, isCompensation := registry.CompensatedTaskName(taskName); isCompensation {
+		taskName = original
+	}
+
+	// Map items retry like their map step
+	taskDef, exists := def.Step(taskName)
+	if !exists || taskDef.RetryPolicy == nil {
+		return w.defaultRetryPolicy()
+	}
+
+	return taskDef.RetryPolicy.Merge(w.defaultRetryPolicy())
 }
 
-// handlerTaskFailure handles task failure with retry logic
-func (w *WorkflowWorker) handleTaskFailure(ctx context.Context, task model.Tasks, retryCount int32, taskErr error) {
+// handlerTaskFailure handles task failure with retry logic. The resulting
+// state change and its activity log are committed together
+func (w *WorkflowWorker) handleTaskFailure(ctx context.Context, task model.Tasks, retryCount int32, policy registry.RetryPolicy, taskErr error) {
 	// Check if we should retry
-	if retryCount >= int32(w.maxRetries) {
-		// Max retries reached - mark as FAILED
+	if !policy.ShouldRetry(int(retryCount)+1, taskErr) {
+		reason := "Max retries exceeded"
+		if !policy.IsRetryable(taskErr) {
+			reason = "Non-retryable error"
+		}
+
+		// No more retries - mark as FAILED
 		logger.Warn().
 			Int64("task_id", task.ID).
 			Int32("retry_count", retryCount).
-			Msg("Task failed after max retries")
-
-		w.repo.UpdateTaskStatus(ctx, int(task.ID), "FAILED")
-
-		// Log failure in activity logs
-		eventTypeFailed := "TASK_FAILED"
-		failureDefaults := map[string]any{
-			"task_id":     task.ID,
-			"task_name":   task.TaskName,
-			"retry_count": retryCount,
-			"reason":      "Max retries exceeded",
-			"error":       taskErr.Error(),
-		}
-		failureDefaultsJSON, _ := json.Marshal(failureDefaults)
-		failureDefaultsStr := string(failureDefaultsJSON)
-		w.repo.CreateActivityLog(ctx, &model.ActivityLogs{
-			WorkflowInstanceID: task.WorkflowInstanceID,
-			TaskName:           &task.TaskName,
-			EventType:          &eventTypeFailed,
-			Details:            &failureDefaultsStr,
+			Str("reason", reason).
+			Msg("Task failed permanently")
+
+		err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+			wf, err := w.repo.LockWorkflow(ctx, task.WorkflowInstanceID)
+			if err != nil {
+				return err
+			}
+
+			// Another worker owns the task now; its outcome decides the step
+			failed, err := w.repo.FailTask(ctx, int(task.ID), w.workerID)
+			if err != nil {
+				return err
+			}
+			if !failed {
+				return errLeaseLost
+			}
+
+			// Keep the last error on the task row
+			if err := w.repo.UpdateTaskErrorMessage(ctx, int(task.ID), taskErrorMessage(taskErr)); err != nil {
+				return err
+			}
+
+			// Log failure in activity logs
+			if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TASK_FAILED", map[string]any{
+				"task_id":     task.ID,
+				"task_name":   task.TaskName,
+				"retry_count": retryCount,
+				"reason":      reason,
+				"error":       taskErr.Error(),
+			}); err != nil {
+				return err
+			}
+
+			// Compensate completed steps, or mark the workflow as FAILED
+			return w.failWorkflow(ctx, wf, task)
 		})
-
-		// Mark workflow as FAILED
-		w.repo.UpdateWorkflowStatus(ctx, task.WorkflowInstanceID, "FAILED")
+		if errors.Is(err, errLeaseLost) {
+			logger.Warn().Int64("task_id", task.ID).Str("worker_id", w.workerID).Msg("Task lease lost, not failing task")
+			return
+		}
+		if err != nil {
+			logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to mark task as failed")
+		}
 
 		return
 	}
 
 	// Increment retry count and schedule retry
 	newRetryCount := int(retryCount) + 1
-	if err := w.repo.UpdateTaskRetryCount(ctx, int(task.ID), newRetryCount); err != nil {
-		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to update retry count")
+
+	// Calculate backoff delay from the task's retry policy
+	backoffDelay := policy.Backoff(newRetryCount)
+	scheduledAt := time.Now().Add(backoffDelay)
+
+	// Back to PENDING with scheduled_at; the goroutine returns immediately and
+	// the retry survives a restart because it is persisted
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		scheduled, err := w.repo.ScheduleTaskRetry(ctx, int(task.ID), w.workerID, newRetryCount, scheduledAt)
+		if err != nil {
+			return err
+		}
+		if !scheduled {
+			// Reaped and possibly running on another worker - leave it alone
+			return errLeaseLost
+		}
+
+		// Keep the last error on the task row
+		if err := w.repo.UpdateTaskErrorMessage(ctx, int(task.ID), taskErrorMessage(taskErr)); err != nil {
+			return err
+		}
+
+		// Log retry in activity logs
+		return w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TASK_RETRY", map[string]any{
+			"task_id":       task.ID,
+			"task_name":     task.TaskName,
+			"retry_count":   newRetryCount,
+			"backoff_delay": backoffDelay.String(),
+			"scheduled_at":  scheduledAt.Format(time.RFC3339),
+			"error":         taskErr.Error(),
+		})
+	})
+	if errors.Is(err, errLeaseLost) {
+		logger.Warn().Int64("task_id", task.ID).Str("worker_id", w.workerID).Msg("Task lease lost, not scheduling retry")
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Int64("task_id", task.ID).Msg("Failed to schedule task retry")
 		return
 	}
 
-	// Calculate exponential backoff delay (2^retryCount second)
-	backoffDelay := time.Duration(1<<uint(newRetryCount)) * time.Second
 	logger.Info().
 		Int64("task_id", task.ID).
 		Int("retry_count", newRetryCount).
 		Dur("backoff_delay", backoffDelay).
+		Time("scheduled_at", scheduledAt).
 		Str("error", taskErr.Error()).
 		Msg("Task failed, scheduling retry with exponential backoff")
-
-	// Update to FAILED status temporarily
-	w.repo.UpdateTaskStatus(ctx, int(task.ID), "FAILED")
-
-	// Log retry in activity logs
-	eventTypeRetry := "TASK_RETRY"
-	retryDetails := map[string]any{
-		"task_id":       task.ID,
-		"task_name":     task.TaskName,
-		"retry_count":   newRetryCount,
-		"backoff_delay": backoffDelay.String(),
-		"error":         taskErr.Error(),
-	}
-	retryDetailsJSON, _ := json.Marshal(retryDetails)
-	retryDetailsStr := string(retryDetailsJSON)
-	w.repo.CreateActivityLog(ctx, &model.ActivityLogs{
-		WorkflowInstanceID: task.WorkflowInstanceID,
-		TaskName:           &task.TaskName,
-		EventType:          &eventTypeRetry,
-		Details:            &retryDetailsStr,
-	})
-
-	// Sleep for exponential backoff
-	time.Sleep(backoffDelay)
-
-	// Reset to PENDING so worker can pick it up again
-	w.repo.UpdateTaskStatus(ctx, int(task.ID), "PENDING")
 }
 
 // handleTaskSuccess handles successfully task completion
 func (w *WorkflowWorker) handleTaskSuccess(ctx context.Context, task model.Tasks, retryCount int32) {
-	// Update task output payload if executor modified it
-	if task.OutputPayload != nil {
-		// Optionally save output payload to database
-		// w.repo.UpdateTaskOutput(ctx, int(task.ID), *task.OutputPayload)
+	w.completeTask(ctx, task, retryCount, "")
+}
+
+// recordStep adds the output of a completed task to the workflow state.
+// Map items are recorded through their map step's collected output
+func (w *WorkflowWorker) recordStep(ctx context.Context, wf *model.WorkflowInstances, task model.Tasks) error {
+	if _, _, isItem := registry.MapItemStep(task.TaskName); isItem {
+		return nil
 	}
 
-	// Mark task as COMPLETED
-	w.repo.UpdateTaskStatus(ctx, int(task.ID), "COMPLETED")
+	state, err := registry.ParseWorkflowState(wf.State, wf.CurrentInput)
+	if err != nil {
+		// Don't block the workflow on a broken state; rebuild it from here
+		logger.Warn().Err(err).Str("workflow_id", wf.ID).Msg("Resetting unreadable workflow state")
+		state = registry.NewWorkflowState(wf.CurrentInput)
+	}
+	state.SetStep(task.TaskName, task.OutputPayload)
 
-	// Log task completion
-	eventTypeComplete := "TASK_COMPLETED"
-	detailsCompleteMap := map[string]any{
-		"task_id":     task.ID,
-		"task_name":   task.TaskName,
-		"workflow_id": task.WorkflowInstanceID,
-		"status":      "success",
-		"retry_count": retryCount,
+	stateStr, err := state.Encode()
+	if err != nil {
+		return err
 	}
-	detailsCompleteJSON, _ := json.Marshal(detailsCompleteMap)
-	detailsComplete := string(detailsCompleteJSON)
-	w.repo.CreateActivityLog(ctx, &model.ActivityLogs{
-		WorkflowInstanceID: task.WorkflowInstanceID,
-		TaskName:           &task.TaskName,
-		EventType:          &eventTypeComplete,
-		Details:            &detailsComplete,
-	})
+	if err := w.repo.UpdateWorkflowState(ctx, wf.ID, stateStr); err != nil {
+		return err
+	}
+	wf.State = &stateStr
+
+	return nil
+}
+
+// completeTask completes a task and schedules what follows it (see
+// orchestrateNextStep for route). Completing the task, logging it and
+// scheduling the next step commit atomically; if this worker lost its claim
+// meanwhile, nothing is written
+func (w *WorkflowWorker) completeTask(ctx context.Context, task model.Tasks, retryCount int32, route string) {
+	err := w.repo.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the instance first so transitions of parallel tasks run one at a time
+		wf, err := w.repo.LockWorkflow(ctx, task.WorkflowInstanceID)
+		if err != nil {
+			return fmt.Errorf("lock workflow: %w", err)
+		}
+
+		// Mark task as COMPLETED and save its output payload
+		completed, err := w.repo.CompleteTask(ctx, int(task.ID), w.workerID, task.OutputPayload)
+		if err != nil {
+			return err
+		}
+		if !completed {
+			return errLeaseLost
+		}
+		if err := w.recordStep(ctx, wf, task); err != nil {
+			return err
+		}
 
-	// Orchestrate next step
-	w.orchestrateNextStep(ctx, task)
+		// Log task completion
+		if err := w.logActivity(ctx, task.WorkflowInstanceID, &task.TaskName, "TASK_COMPLETED", map[string]any{
+			"task_id":     task.ID,
+			"task_name":   task.TaskName,
+			"workflow_id": task.WorkflowInstanceID,
+			"status":      "success",
+			"retry_count": retryCount,
+		}); err != nil {
+			return err
+		}
+
+		// Orchestrate next step
+		return w.orchestrateNextStep(ctx, wf, task, route)
+	})
+	if err != nil {
+		// The task stays claimed; once the lease expires it is retried
+		logger.Error().Err(err).
+			Int64("task_id", task.ID).
+			Str("workflow_id", task.WorkflowInstanceID).
+			Msg("Failed to complete task")
+	}
 }
@@ -0,0 +1,327 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/config"
+	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
+)
+
+func validWorkerConfig() config.WorkerConfig {
+	return config.WorkerConfig{
+		WorkerID:          "worker-1",
+		PollInterval:      5 * time.Second,
+		BatchSize:         10,
+		MaxConcurrency:    10,
+		TaskTimeout:       30 * time.Second,
+		MaxRetries:        3,
+		LeaseDuration:     30 * time.Second,
+		HeartbeatInterval: 10 * time.Second,
+		ReaperInterval:    15 * time.Second,
+		ShutdownGrace:     25 * time.Second,
+	}
+}
+
+func TestNewWorkflowWorkerConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.WorkerConfig)
+		wantErr string
+	}{
+		{"valid", func(cfg *config.WorkerConfig) {}, ""},
+		{"no shutdown grace", func(cfg *config.WorkerConfig) { cfg.ShutdownGrace = 0 }, ""},
+		{"empty worker ID", func(cfg *config.WorkerConfig) { cfg.WorkerID = "" }, "worker ID"},
+		{"zero batch size", func(cfg *config.WorkerConfig) { cfg.BatchSize = 0 }, "batch size"},
+		{"zero max concurrency", func(cfg *config.WorkerConfig) { cfg.MaxConcurrency = 0 }, "max concurrency"},
+		{"negative max concurrency", func(cfg *config.WorkerConfig) { cfg.MaxConcurrency = -1 }, "max concurrency"},
+		{"zero poll interval", func(cfg *config.WorkerConfig) { cfg.PollInterval = 0 }, "poll interval"},
+		{"zero task timeout", func(cfg *config.WorkerConfig) { cfg.TaskTimeout = 0 }, "task timeout"},
+		{"zero lease duration", func(cfg *config.WorkerConfig) { cfg.LeaseDuration = 0 }, "lease duration"},
+		{"negative heartbeat", func(cfg *config.WorkerConfig) { cfg.HeartbeatInterval = -time.Second }, "heartbeat interval"},
+		{"zero reaper interval", func(cfg *config.WorkerConfig) { cfg.ReaperInterval = 0 }, "reaper interval"},
+		{"heartbeat equal to lease", func(cfg *config.WorkerConfig) { cfg.HeartbeatInterval = cfg.LeaseDuration }, "shorter than lease duration"},
+		{"heartbeat longer than lease", func(cfg *config.WorkerConfig) { cfg.HeartbeatInterval = time.Minute }, "shorter than lease duration"},
+		{"negative shutdown grace", func(cfg *config.WorkerConfig) { cfg.ShutdownGrace = -time.Second }, "shutdown grace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validWorkerConfig()
+			tt.modify(&cfg)
+
+			w, err := NewWorkflowWorker(nil, nil, nil, &cfg)
+			if tt.wantErr == "" {
+				if err != nil || w == nil {
+					t.Fatalf("NewWorkflowWorker = %v, %v; want a worker", w, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewWorkflowWorker error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// attempt is the outcome of one run of a task
+type attempt func() error
+
+func succeed() error { return nil }
+
+func fail(err error) attempt {
+	return func() error { return err }
+}
+
+func panicWith(v any) attempt {
+	return func() error { panic(v) }
+}
+
+func TestWorkerTaskLifecycle(t *testing.T) {
+	errGateway := errors.New("gateway down")
+	errDeclined := errors.New("card declined")
+	failed := []string{"TASK_STARTED", "WORKFLOW_STARTED", "TASK_FAILED"}
+
+	tests := []struct {
+		name         string
+		opts         []registry.TaskOption
+		attempts     []attempt
+		wantTask     model.TasksStatus
+		wantRetries  int32
+		wantWorkflow model.WorkflowInstancesStatus
+		wantEvents   []string
+		wantError    []string // error_message ต้องมีทุกข้อความ
+	}{
+		{
+			name:         "succeeds first time",
+			attempts:     []attempt{succeed},
+			wantTask:     model.TasksStatus_Completed,
+			wantWorkflow: model.WorkflowInstancesStatus_Completed,
+			wantEvents: []string{
+				"TASK_STARTED", "WORKFLOW_STARTED", "TASK_COMPLETED",
+				"TASK_STARTED", "TASK_COMPLETED", "WORKFLOW_COMPLETED",
+			},
+		},
+		{
+			name:         "retries then succeeds",
+			attempts:     []attempt{fail(errGateway), fail(errGateway), succeed},
+			wantTask:     model.TasksStatus_Completed,
+			wantRetries:  2,
+			wantWorkflow: model.WorkflowInstancesStatus_Completed,
+			wantEvents: []string{
+				"TASK_STARTED", "WORKFLOW_STARTED", "TASK_RETRY",
+				"TASK_STARTED", "TASK_RETRY",
+				"TASK_STARTED", "TASK_COMPLETED",
+				"TASK_STARTED", "TASK_COMPLETED", "WORKFLOW_COMPLETED",
+			},
+		},
+		{
+			name:         "non-retryable error",
+			attempts:     []attempt{fail(registry.NonRetryable(errDeclined))},
+			wantTask:     model.TasksStatus_Failed,
+			wantWorkflow: model.WorkflowInstancesStatus_Failed,
+			wantEvents:   failed,
+			wantError:    []string{"card declined"},
+		},
+		{
+			name:         "error listed in the policy",
+			opts:         []registry.TaskOption{registry.WithRetryPolicy(registry.RetryPolicy{NonRetryableErrors: []error{errDeclined}})},
+			attempts:     []attempt{fail(errDeclined)},
+			wantTask:     model.TasksStatus_Failed,
+			wantWorkflow: model.WorkflowInstancesStatus_Failed,
+			wantEvents:   failed,
+		},
+		{
+			name:         "attempts used up",
+			opts:         []registry.TaskOption{registry.WithRetryPolicy(registry.RetryPolicy{MaxAttempts: 2})},
+			attempts:     []attempt{fail(errGateway), fail(errGateway)},
+			wantTask:     model.TasksStatus_Failed,
+			wantRetries:  1,
+			wantWorkflow: model.WorkflowInstancesStatus_Failed,
+			wantEvents: []string{
+				"TASK_STARTED", "WORKFLOW_STARTED", "TASK_RETRY",
+				"TASK_STARTED", "TASK_FAILED",
+			},
+			wantError: []string{"gateway down"},
+		},
+		{
+			name:         "panic is retried",
+			attempts:     []attempt{panicWith("nil map"), succeed},
+			wantTask:     model.TasksStatus_Completed,
+			wantRetries:  1,
+			wantWorkflow: model.WorkflowInstancesStatus_Completed,
+			wantEvents: []string{
+				"TASK_STARTED", "WORKFLOW_STARTED", "TASK_PANICKED", "TASK_RETRY",
+				"TASK_STARTED", "TASK_COMPLETED",
+				"TASK_STARTED", "TASK_COMPLETED", "WORKFLOW_COMPLETED",
+			},
+			wantError: []string{"task panicked: nil map", "goroutine"},
+		},
+		{
+			name:         "panics until attempts used up",
+			opts:         []registry.TaskOption{registry.WithRetryPolicy(registry.RetryPolicy{MaxAttempts: 2})},
+			attempts:     []attempt{panicWith("nil map"), panicWith(errDeclined)},
+			wantTask:     model.TasksStatus_Failed,
+			wantRetries:  1,
+			wantWorkflow: model.WorkflowInstancesStatus_Failed,
+			wantEvents: []string{
+				"TASK_STARTED", "WORKFLOW_STARTED", "TASK_PANICKED", "TASK_RETRY",
+				"TASK_STARTED", "TASK_PANICKED", "TASK_FAILED",
+			},
+			wantError: []string{"task panicked: card declined", "runTaskFunc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var repo *fakeRepo
+			runs := 0
+			charge := func(ctx context.Context, task *model.Tasks) error {
+				// ทุก attempt หลังครั้งแรกต้องถูก mark RETRYING ก่อนรัน
+				if status := taskStatus(repo.tasks[task.ID]); runs > 0 && status != model.TasksStatus_Retrying {
+					t.Errorf("attempt %d ran with status %s, want RETRYING", runs+1, status)
+				}
+				runs++
+				return tt.attempts[runs-1]()
+			}
+			notify := func(ctx context.Context, task *model.Tasks) error { return nil }
+
+			w, r := newTestWorker(t, func(reg *registry.WorkflowRegistry) {
+				reg.NewWorkflow("Order").
+					AddTask("Charge", charge, tt.opts...).
+					AddTask("Notify", notify).
+					MustBuild()
+			})
+			repo = r
+			wfID := repo.startWorkflow("Order", "Charge", `{"amount":100}`)
+
+			for runReady(t, w, repo) > 0 {
+				task := repo.task(t, wfID, "Charge")
+				if taskStatus(task) != model.TasksStatus_Pending {
+					continue
+				}
+
+				// retry รอ backoff และไม่ถือ claim ไว้ระหว่างรอ
+				if task.ScheduledAt == nil || !task.ScheduledAt.After(time.Now()) {
+					t.Fatalf("retry scheduled at %v, want in the future", task.ScheduledAt)
+				}
+				if task.ClaimedBy != nil || task.LeaseExpiresAt != nil {
+					t.Fatalf("retry still holds its claim: %v until %v", task.ClaimedBy, task.LeaseExpiresAt)
+				}
+				if n := runReady(t, w, repo); n != 0 {
+					t.Fatalf("ran %d tasks before the backoff elapsed", n)
+				}
+				repo.makeReady(task.ID)
+			}
+
+			if runs != len(tt.attempts) {
+				t.Errorf("Charge ran %d times, want %d", runs, len(tt.attempts))
+			}
+			task := repo.task(t, wfID, "Charge")
+			if got := taskStatus(task); got != tt.wantTask {
+				t.Errorf("Charge status = %s, want %s", got, tt.wantTask)
+			}
+			if *task.RetryCount != tt.wantRetries {
+				t.Errorf("Charge retry_count = %d, want %d", *task.RetryCount, tt.wantRetries)
+			}
+			if got := workflowStatus(repo.workflows[wfID]); got != tt.wantWorkflow {
+				t.Errorf("workflow status = %s, want %s", got, tt.wantWorkflow)
+			}
+			if got := repo.events(wfID); !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", got, tt.wantEvents)
+			}
+			for _, want := range tt.wantError {
+				if task.ErrorMessage == nil || !strings.Contains(*task.ErrorMessage, want) {
+					t.Errorf("error_message = %v, want it to contain %q", task.ErrorMessage, want)
+				}
+			}
+
+			// workflow ที่ fail ต้องไม่สร้าง step ถัดไป
+			if tt.wantWorkflow == model.WorkflowInstancesStatus_Failed {
+				if tasks, _ := repo.GetTasksByWorkflowID(context.Background(), wfID); len(tasks) != 1 {
+					t.Errorf("workflow has %d tasks, want only Charge", len(tasks))
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerRetryBackoff(t *testing.T) {
+	w, repo := newTestWorker(t, func(reg *registry.WorkflowRegistry) {
+		reg.NewWorkflow("Order").
+			AddTask("Charge", func(ctx context.Context, task *model.Tasks) error {
+				return errors.New("gateway down")
+			}).
+			MustBuild()
+	})
+	wfID := repo.startWorkflow("Order", "Charge", "{}")
+
+	// default policy: 2s แล้วคูณ 2 ทุกครั้ง
+	for retry, want := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		before := time.Now()
+		runReady(t, w, repo)
+		task := repo.task(t, wfID, "Charge")
+
+		if *task.RetryCount != int32(retry+1) {
+			t.Fatalf("retry_count = %d, want %d", *task.RetryCount, retry+1)
+		}
+		if got := task.ScheduledAt.Sub(before); got < want || got > want+time.Second {
+			t.Errorf("retry %d scheduled after %v, want %v", retry+1, got, want)
+		}
+		repo.makeReady(task.ID)
+	}
+}
+
+func TestWorkerPassesOutputs(t *testing.T) {
+	chargeOutput := `{"charge_id":"ch_1"}`
+	notifyOutput := `{"sent":true}`
+
+	w, repo := newTestWorker(t, func(reg *registry.WorkflowRegistry) {
+		reg.NewWorkflow("Order").
+			AddTask("Charge", func(ctx context.Context, task *model.Tasks) error {
+				task.OutputPayload = &chargeOutput
+				return nil
+			}).
+			AddTask("Notify", func(ctx context.Context, task *model.Tasks) error {
+				if task.InputPayload == nil || *task.InputPayload != chargeOutput {
+					t.Errorf("Notify input = %v, want %s", task.InputPayload, chargeOutput)
+				}
+				task.OutputPayload = &notifyOutput
+				return nil
+			}).
+			MustBuild()
+	})
+	wfID := repo.startWorkflow("Order", "Charge", `{"amount":100}`)
+
+	for runReady(t, w, repo) > 0 {
+	}
+
+	if got := repo.task(t, wfID, "Charge").OutputPayload; got == nil || *got != chargeOutput {
+		t.Errorf("Charge output_payload = %v, want %s", got, chargeOutput)
+	}
+
+	// ผลลัพธ์ของ workflow คือ output ของ step สุดท้าย
+	wf := repo.workflows[wfID]
+	if wf.CurrentOutput == nil || *wf.CurrentOutput != notifyOutput {
+		t.Errorf("workflow current_output = %v, want %s", wf.CurrentOutput, notifyOutput)
+	}
+
+	state, err := registry.ParseWorkflowState(wf.State, wf.CurrentInput)
+	if err != nil {
+		t.Fatalf("ParseWorkflowState: %v", err)
+	}
+	want := map[string]string{"Charge": chargeOutput, "Notify": notifyOutput}
+	for step, output := range want {
+		if got := string(state.Steps[step]); got != output {
+			t.Errorf("state step %s = %s, want %s", step, got, output)
+		}
+	}
+	if got := string(state.Input); got != `{"amount":100}` {
+		t.Errorf("state input = %s, want the workflow input", got)
+	}
+}
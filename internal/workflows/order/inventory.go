@@ -0,0 +1,64 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// Reservation is the output of one ReserveStock item and the input of its compensation
+type Reservation struct {
+	StockReserved bool   `json:"stock_reserved"`
+	SKU           string `json:"sku" validate:"required"`
+	Quantity      int    `json:"quantity"`
+	ReservedAt    string `json:"reserved_at"`
+}
+
+// Release is the output of ReleaseStock
+type Release struct {
+	StockReleased bool   `json:"stock_released"`
+	SKU           string `json:"sku"`
+	Quantity      int    `json:"quantity"`
+	ReleasedAt    string `json:"released_at"`
+}
+
+// reserveStock reserves one line item; its input is an element of "items"
+func reserveStock(ctx context.Context, input LineItem) (Reservation, error) {
+	logger.Info().Str("task", "ReserveStock").Msg("Reserving stock")
+
+	time.Sleep(1 * time.Second)
+
+	output := Reservation{
+		StockReserved: true,
+		SKU:           input.SKU,
+		Quantity:      input.Quantity,
+		ReservedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	logger.Info().
+		Func(taskFields(ctx)).
+		Msg("Stock reserved successfully")
+
+	return output, nil
+}
+
+// releaseStock compensates reserveStock; its input is reserveStock's output
+func releaseStock(ctx context.Context, input Reservation) (Release, error) {
+	logger.Info().Str("task", "ReleaseStock").Msg("Releasing stock")
+
+	time.Sleep(1 * time.Second)
+
+	output := Release{
+		StockReleased: true,
+		SKU:           input.SKU,
+		Quantity:      input.Quantity,
+		ReleasedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	logger.Info().
+		Func(taskFields(ctx)).
+		Msg("Stock released successfully")
+
+	return output, nil
+}
@@ -2,41 +2,54 @@ package order
 
 import (
 	"context"
-	"encoding/json"
 	"time"
 
-	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
+	"github.com/parinyadagon/go-workflow/internal/core/registry"
 	"github.com/parinyadagon/go-workflow/pkg/logger"
 )
 
-func sendEmail(ctx context.Context, task *model.Tasks) error {
+// EmailInput is the input of SendEmail: DeductMoney's output merged with ReserveStock's
+type EmailInput struct {
+	OrderID       string `json:"order_id"`
+	TransactionID string `json:"transaction_id"`
+}
+
+// EmailResult is the output of SendEmail
+type EmailResult struct {
+	EmailSent   bool    `json:"email_sent"`
+	Recipient   string  `json:"recipient"`
+	SentAt      string  `json:"sent_at"`
+	OrderID     string  `json:"order_id"`
+	Transaction string  `json:"transaction"`
+	Amount      float64 `json:"amount"`
+}
+
+func sendEmail(ctx context.Context, input EmailInput) (EmailResult, error) {
 	logger.Info().Str("task", "SendEmail").Msg("Sending email")
 
-	var input map[string]interface{}
-	if task.InputPayload != nil {
-		if err := json.Unmarshal([]byte(*task.InputPayload), &input); err != nil {
-			return err
+	// The amount comes from the original order, not from the previous steps
+	var order OrderInput
+	if state, ok := registry.StateFromContext(ctx); ok {
+		if err := state.DecodeInput(&order); err != nil {
+			return EmailResult{}, registry.NonRetryable(err)
 		}
 	}
 
 	time.Sleep(1 * time.Second)
 
-	output := map[string]interface{}{
-		"email_sent":  true,
-		"recipient":   "customer@example.com",
-		"sent_at":     time.Now().Format(time.RFC3339),
-		"order_id":    input["order_id"],
-		"transaction": input["transaction_id"],
+	output := EmailResult{
+		EmailSent:   true,
+		Recipient:   "customer@example.com",
+		SentAt:      time.Now().Format(time.RFC3339),
+		OrderID:     input.OrderID,
+		Transaction: input.TransactionID,
+		Amount:      order.Amount,
 	}
-	outputJSON, _ := json.Marshal(output)
-	outputStr := string(outputJSON)
-	task.OutputPayload = &outputStr
 
 	logger.Info().
-		Str("task_name", task.TaskName).
-		Int64("task_id", task.ID).
-		Str("recipient", output["recipient"].(string)).
+		Func(taskFields(ctx)).
+		Str("recipient", output.Recipient).
 		Msg("Email sent successfully")
 
-	return nil
+	return output, nil
 }
@@ -2,46 +2,78 @@ package order
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"time"
 
-	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
 	"github.com/parinyadagon/go-workflow/pkg/logger"
 )
 
-func deductMoney(ctx context.Context, task *model.Tasks) error {
-	logger.Info().Str("task", "DeductMoney").Msg("Deducting money")
+// PaymentInput is the input of DeductMoney, from ValidateOrder or ManualReview
+type PaymentInput struct {
+	OrderID string  `json:"order_id" validate:"required"`
+	Amount  float64 `json:"amount" validate:"gt=0"`
+}
 
-	var input map[string]interface{}
-	if task.InputPayload != nil {
-		if err := json.Unmarshal([]byte(*task.InputPayload), &input); err != nil {
-			return err
-		}
-	}
+// Payment is the output of DeductMoney and the input of its compensation
+type Payment struct {
+	PaymentStatus string  `json:"payment_status"`
+	TransactionID string  `json:"transaction_id" validate:"required"`
+	OrderID       string  `json:"order_id"`
+	Amount        float64 `json:"amount"`
+	DeductedAt    string  `json:"deducted_at"`
+}
+
+// Refund is the output of RefundMoney
+type Refund struct {
+	RefundStatus  string  `json:"refund_status"`
+	TransactionID string  `json:"transaction_id"`
+	Amount        float64 `json:"amount"`
+	RefundedAt    string  `json:"refunded_at"`
+}
+
+func deductMoney(ctx context.Context, input PaymentInput) (Payment, error) {
+	logger.Info().Str("task", "DeductMoney").Msg("Deducting money")
 
 	time.Sleep(2 * time.Second)
 
 	// Random failure (20%)
 	if time.Now().Unix()%10 < 2 {
-		return errors.New("payment gateway timeout")
+		return Payment{}, errors.New("payment gateway timeout")
 	}
 
-	output := map[string]interface{}{
-		"payment_status": "SUCCESS",
-		"transaction_id": "TXN" + time.Now().Format("20060102150405"),
-		"amount":         input["amount"],
-		"deducted_at":    time.Now().Format(time.RFC3339),
+	output := Payment{
+		PaymentStatus: "SUCCESS",
+		TransactionID: "TXN" + time.Now().Format("20060102150405"),
+		OrderID:       input.OrderID,
+		Amount:        input.Amount,
+		DeductedAt:    time.Now().Format(time.RFC3339),
 	}
-	outputJSON, _ := json.Marshal(output)
-	outputStr := string(outputJSON)
-	task.OutputPayload = &outputStr
 
 	logger.Info().
-		Str("task_name", task.TaskName).
-		Int64("task_id", task.ID).
-		Str("transaction_id", output["transaction_id"].(string)).
+		Func(taskFields(ctx)).
+		Str("transaction_id", output.TransactionID).
 		Msg("Payment processed successfully")
 
-	return nil
+	return output, nil
+}
+
+// refundMoney compensates deductMoney; its input is deductMoney's output
+func refundMoney(ctx context.Context, input Payment) (Refund, error) {
+	logger.Info().Str("task", "RefundMoney").Msg("Refunding money")
+
+	time.Sleep(1 * time.Second)
+
+	output := Refund{
+		RefundStatus:  "SUCCESS",
+		TransactionID: input.TransactionID,
+		Amount:        input.Amount,
+		RefundedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	logger.Info().
+		Func(taskFields(ctx)).
+		Str("transaction_id", input.TransactionID).
+		Msg("Payment refunded successfully")
+
+	return output, nil
 }
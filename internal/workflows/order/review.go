@@ -0,0 +1,44 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/parinyadagon/go-workflow/pkg/logger"
+)
+
+// highValueAmount is the order amount from which an order needs manual review
+const highValueAmount = 10000
+
+// ReviewedOrder is the output of ManualReview
+type ReviewedOrder struct {
+	Reviewed   bool    `json:"reviewed"`
+	OrderID    string  `json:"order_id"`
+	Amount     float64 `json:"amount"`
+	ReviewedAt string  `json:"reviewed_at"`
+}
+
+// isHighValue picks the ManualReview branch after ValidateOrder
+func isHighValue(output map[string]any) bool {
+	amount, ok := output["amount"].(float64)
+	return ok && amount >= highValueAmount
+}
+
+func manualReview(ctx context.Context, input ValidatedOrder) (ReviewedOrder, error) {
+	logger.Info().Str("task", "ManualReview").Msg("Reviewing high-value order")
+
+	time.Sleep(1 * time.Second)
+
+	output := ReviewedOrder{
+		Reviewed:   true,
+		OrderID:    input.OrderID,
+		Amount:     input.Amount,
+		ReviewedAt: time.Now().Format(time.RFC3339),
+	}
+
+	logger.Info().
+		Func(taskFields(ctx)).
+		Msg("Order approved by review")
+
+	return output, nil
+}
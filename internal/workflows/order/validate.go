@@ -2,53 +2,61 @@ package order
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"time"
 
-	"github.com/parinyadagon/go-workflow/gen/go_flow/model"
 	"github.com/parinyadagon/go-workflow/pkg/logger"
 )
 
-func validateOrder(ctx context.Context, task *model.Tasks) error {
-	logger.Info().Str("task", "ValidateOrder").Msg("Validating order")
+// OrderInput is the input payload of OrderProcess
+type OrderInput struct {
+	OrderID string     `json:"order_id" validate:"required"`
+	Amount  float64    `json:"amount" validate:"gt=0"`
+	Items   []LineItem `json:"items" validate:"dive"`
+}
 
-	var input map[string]interface{}
-	if task.InputPayload != nil {
-		if err := json.Unmarshal([]byte(*task.InputPayload), &input); err != nil {
-			return err
-		}
-	}
+// LineItem is one line of an order, reserved by ReserveStock
+type LineItem struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
 
-	time.Sleep(1 * time.Second)
+// ValidatedOrder is the output of ValidateOrder
+type ValidatedOrder struct {
+	Validated   bool       `json:"validated"`
+	OrderID     string     `json:"order_id"`
+	Amount      float64    `json:"amount"`
+	Items       []LineItem `json:"items"`
+	ValidatedAt string     `json:"validated_at"`
+}
 
-	// Validation logic
-	if orderID, ok := input["order_id"].(string); ok && orderID == "" {
-		return errors.New("order_id is required")
-	}
-	if amount, ok := input["amount"].(float64); ok && amount <= 0 {
-		return errors.New("amount must be positive")
-	}
+func validateOrder(ctx context.Context, input OrderInput) (ValidatedOrder, error) {
+	logger.Info().Str("task", "ValidateOrder").Msg("Validating order")
+
+	time.Sleep(1 * time.Second)
 
 	// Random failure for testing (30%)
 	if time.Now().Unix()%10 < 3 {
-		return errors.New("validation failed randomly")
+		return ValidatedOrder{}, errors.New("validation failed randomly")
+	}
+
+	// Line items are reserved one by one (ReserveStock)
+	items := input.Items
+	if items == nil {
+		items = []LineItem{}
 	}
 
-	output := map[string]interface{}{
-		"validated":    true,
-		"order_id":     input["order_id"],
-		"amount":       input["amount"],
-		"validated_at": time.Now().Format(time.RFC3339),
+	output := ValidatedOrder{
+		Validated:   true,
+		OrderID:     input.OrderID,
+		Amount:      input.Amount,
+		Items:       items,
+		ValidatedAt: time.Now().Format(time.RFC3339),
 	}
-	outputJSON, _ := json.Marshal(output)
-	outputStr := string(outputJSON)
-	task.OutputPayload = &outputStr
 
 	logger.Info().
-		Str("task_name", task.TaskName).
-		Int64("task_id", task.ID).
+		Func(taskFields(ctx)).
 		Msg("Order validated successfully")
 
-	return nil
+	return output, nil
 }
@@ -1,14 +1,62 @@
 package order
 
 import (
+	"context"
+	"time"
+
 	"github.com/parinyadagon/go-workflow/internal/core/registry"
+	"github.com/rs/zerolog"
 )
 
 // Register registers the OrderProcess workflow with all its tasks
 func Register(reg *registry.WorkflowRegistry) {
+	// Activities usable from declarative workflows (definitions/*.yaml)
+	reg.MustRegisterActivity("ValidateOrder", registry.Typed(validateOrder))
+	reg.MustRegisterActivity("DeductMoney", registry.Typed(deductMoney))
+	reg.MustRegisterActivity("RefundMoney", registry.Typed(refundMoney))
+	reg.MustRegisterActivity("SendEmail", registry.Typed(sendEmail))
+
 	reg.NewWorkflow("OrderProcess").
-		AddTask("ValidateOrder", validateOrder).
-		AddTask("DeductMoney", deductMoney).
-		AddTask("SendEmail", sendEmail).
+		Timeout(30*time.Minute).
+		// POST /workflows rejects orders that ValidateOrder could not decode
+		Input(registry.InputOf[OrderInput]()).
+		AddTask("ValidateOrder", registry.Typed(validateOrder)).
+		// High-value orders are reviewed before payment
+		Branch(
+			registry.When("ManualReview", isHighValue),
+			registry.Otherwise("DeductMoney"),
+		).
+		AddTask("ManualReview", registry.Typed(manualReview)).
+		// Payment gateway: few attempts, back off slowly
+		AddTask("DeductMoney", registry.Typed(deductMoney), registry.WithRetryPolicy(registry.RetryPolicy{
+			MaxAttempts:        3,
+			InitialInterval:    5 * time.Second,
+			MaxInterval:        1 * time.Minute,
+			BackoffCoefficient: 3,
+		}), registry.WithTimeout(15*time.Second), registry.DependsOn("ManualReview"),
+			// Undo the payment if a later step fails permanently
+			registry.WithCompensation(registry.Typed(refundMoney))).
+		// Runs in parallel with DeductMoney, one task per line item
+		AddMap("ReserveStock", "items", registry.Typed(reserveStock), registry.DependsOn("ValidateOrder"),
+			registry.WithParallelism(5),
+			registry.WithCompensation(registry.Typed(releaseStock))).
+		// Email: cheap to retry, spread retries out with jitter
+		AddTask("SendEmail", registry.Typed(sendEmail), registry.WithRetryPolicy(registry.RetryPolicy{
+			MaxAttempts:        6,
+			InitialInterval:    1 * time.Second,
+			MaxInterval:        30 * time.Second,
+			BackoffCoefficient: 2,
+			Jitter:             0.2,
+		}), registry.DependsOn("DeductMoney", "ReserveStock")).
 		MustBuild()
 }
+
+// taskFields adds the running task to a log line. A function called without
+// registry.Typed has no task in ctx and logs without it
+func taskFields(ctx context.Context) func(e *zerolog.Event) {
+	return func(e *zerolog.Event) {
+		if task, ok := registry.TaskFromContext(ctx); ok {
+			e.Str("task_name", task.TaskName).Int64("task_id", task.ID)
+		}
+	}
+}
@@ -37,3 +37,13 @@ func notifyCustomer(ctx context.Context, task *model.Tasks) error {
 
 	return nil
 }
+
+func rejectRefund(ctx context.Context, task *model.Tasks) error {
+	logger.Info().Str("task", "RejectRefund").Msg("Refund not approved in time, rejecting")
+	time.Sleep(1 * time.Second)
+
+	// Add rejection logic here
+	// Notify customer that the refund request was rejected
+
+	return nil
+}
@@ -1,14 +1,26 @@
 package refund
 
 import (
+	"time"
+
 	"github.com/parinyadagon/go-workflow/internal/core/registry"
 )
 
 // Register registers the RefundProcess workflow with all its tasks
 func Register(reg *registry.WorkflowRegistry) {
+	// Activities usable from declarative workflows (definitions/*.yaml)
+	reg.MustRegisterActivity("ValidateRefund", validateRefund)
+	reg.MustRegisterActivity("ProcessRefund", processRefund)
+	reg.MustRegisterActivity("NotifyCustomer", notifyCustomer)
+
 	reg.NewWorkflow("RefundProcess").
 		AddTask("ValidateRefund", validateRefund).
+		// รอ manager อนุมัติ (POST /workflows/:id/signals/approval) ไม่เกิน 48 ชม.
+		AddSignalWait("ManagerApproval", "approval",
+			registry.WithSignalTimeout(48*time.Hour, "RejectRefund"),
+		).
 		AddTask("ProcessRefund", processRefund).
 		AddTask("NotifyCustomer", notifyCustomer).
+		AddTask("RejectRefund", rejectRefund, registry.DependsOn("ManagerApproval")).
 		MustBuild()
 }
@@ -0,0 +1,295 @@
+// Package cron parses standard five-field cron expressions
+// (minute hour day-of-month month day-of-week) and computes their next run.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Each field is a bit set of the
+// values it matches
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// Day of month and day of week match with OR when both are restricted
+	domStar, dowStar bool
+}
+
+// field describes the range and names of one cron field
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// 7 is accepted for Sunday and folded into 0
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+// macros are the supported shorthand expressions
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxLookahead bounds the search for the next run (e.g. "0 0 30 2 *" never runs)
+const maxLookahead = 5 * 366 * 24 * time.Hour
+
+// Parse parses a five-field cron expression or one of the @yearly, @monthly,
+// @weekly, @daily, @midnight and @hourly macros. Fields accept *, values,
+// names (JAN, MON), ranges (1-5), steps (*/15, 10-50/10) and lists (1,15)
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d: %q", len(fields), expr)
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, _, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, _, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domStar, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowStar, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+// parseField parses one comma separated field into a bit set and reports
+// whether it was a plain *
+func parseField(expr string, f field) (uint64, bool, error) {
+	if expr == "*" {
+		return span(f.min, f.max, 1), true, nil
+	}
+
+	var set uint64
+	for _, part := range strings.Split(expr, ",") {
+		bitsOfPart, err := parsePart(part, f)
+		if err != nil {
+			return 0, false, err
+		}
+		set |= bitsOfPart
+	}
+
+	return set, false, nil
+}
+
+// parsePart parses a value, range or step of a field
+func parsePart(part string, f field) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepExpr)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step in %s field: %q", f.name, part)
+		}
+	}
+
+	var lo, hi int
+	switch {
+	case rangeExpr == "*":
+		lo, hi = f.min, f.max
+	case strings.Contains(rangeExpr, "-"):
+		loExpr, hiExpr, _ := strings.Cut(rangeExpr, "-")
+		var err error
+		if lo, err = parseValue(loExpr, f); err != nil {
+			return 0, err
+		}
+		if hi, err = parseValue(hiExpr, f); err != nil {
+			return 0, err
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range in %s field: %q", f.name, part)
+		}
+	default:
+		value, err := parseValue(rangeExpr, f)
+		if err != nil {
+			return 0, err
+		}
+		lo, hi = value, value
+		// "5/15" means from 5 to the end in steps of 15
+		if hasStep {
+			hi = f.max
+		}
+	}
+
+	return span(lo, hi, step), nil
+}
+
+// parseValue parses a number or name within the range of a field
+func parseValue(expr string, f field) (int, error) {
+	if value, ok := f.names[strings.ToUpper(expr)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field: %q", f.name, expr)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, got %d", f.name, f.min, f.max, value)
+	}
+
+	return value, nil
+}
+
+// span returns the bit set of lo..hi in steps of step
+func span(lo, hi, step int) uint64 {
+	var set uint64
+	for v := lo; v <= hi; v += step {
+		set |= 1 << uint(v)
+	}
+
+	return set
+}
+
+// has reports whether value is in the bit set
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
+
+// Next returns the first run strictly after t, in t's location. It returns
+// the zero time if the expression never matches.
+//
+// Fields match the wall clock of t's location, and each wall-clock time fires
+// at most once: a run that falls in a DST gap (02:30 when clocks jump from
+// 02:00 to 03:00) fires when the gap ends, and a run in a repeated hour fires
+// on its first occurrence only
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Walk the wall clock in UTC, where every minute exists exactly once.
+	// Runs are on whole minutes
+	wall := wallClock(t).Truncate(time.Minute).Add(time.Minute)
+	end := wall.Add(maxLookahead)
+
+	for wall.Before(end) {
+		if !has(s.month, int(wall.Month())) {
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(wall) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.hour, wall.Hour()) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.minute, wall.Minute()) {
+			wall = wall.Add(time.Minute)
+			continue
+		}
+
+		// The first occurrence of a repeated time may already be behind t
+		if run := instant(wall, loc); run.After(t) {
+			return run
+		}
+		wall = wall.Add(time.Minute)
+	}
+
+	return time.Time{}
+}
+
+// wallClock returns the wall-clock reading of t as a UTC time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// instant returns the first instant in loc showing the wall-clock time wall,
+// or the end of the DST gap if that time is skipped in loc
+func instant(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+	if wallClock(t).Equal(wall) {
+		// In a repeated hour time.Date may pick either offset; take the earlier
+		start, _ := t.ZoneBounds()
+		if !start.IsZero() {
+			_, offset := t.Zone()
+			_, before := start.Add(-time.Second).Zone()
+			earlier := t.Add(time.Duration(offset-before) * time.Second)
+			if earlier.Before(t) && wallClock(earlier).Equal(wall) {
+				return earlier
+			}
+		}
+		return t
+	}
+
+	// wall does not exist: time.Date normalized it across the transition
+	start, end := t.ZoneBounds()
+	if wallClock(t).After(wall) {
+		return start
+	}
+	return end
+}
+
+// NextRun parses expr and returns its first run after t in the named time
+// zone (an IANA name such as Asia/Bangkok)
+func NextRun(expr string, timeZone string, t time.Time) (time.Time, error) {
+	schedule, err := Parse(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown time zone %q", timeZone)
+	}
+
+	next := schedule.Next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", expr)
+	}
+
+	return next, nil
+}
+
+// dayMatches applies the cron rule for day of month and day of week: if
+// either is *, both must match; otherwise either may match
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := has(s.dom, t.Day())
+	dowMatch := has(s.dow, int(t.Weekday()))
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,282 @@
+package cron
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"empty", ""},
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"unknown name", "0 0 * FOO *"},
+		{"reversed range", "0 0 * * 5-1"},
+		{"zero step", "*/0 * * * *"},
+		{"bad step", "*/x * * * *"},
+		{"unknown macro", "@every"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		minute []int
+		hour   []int
+		month  []int
+		dow    []int
+	}{
+		{"values", "5 4 * * *", []int{5}, []int{4}, nil, nil},
+		{"range", "0 9-11 * * *", []int{0}, []int{9, 10, 11}, nil, nil},
+		{"step", "*/20 * * * *", []int{0, 20, 40}, nil, nil, nil},
+		{"range with step", "10-50/20 * * * *", []int{10, 30, 50}, nil, nil, nil},
+		{"start with step", "45/5 * * * *", []int{45, 50, 55}, nil, nil, nil},
+		{"list", "0 1,13,22 * * *", []int{0}, []int{1, 13, 22}, nil, nil},
+		{"month names", "0 0 1 JAN,jul *", []int{0}, []int{0}, []int{1, 7}, nil},
+		{"day names", "0 0 * * MON-FRI", []int{0}, []int{0}, nil, []int{1, 2, 3, 4, 5}},
+		{"sunday as 7", "0 0 * * 7", []int{0}, []int{0}, nil, []int{0}},
+		{"macro", "@hourly", []int{0}, nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+
+			check := func(field string, set uint64, want []int) {
+				if want == nil {
+					return
+				}
+				var wantSet uint64
+				for _, v := range want {
+					wantSet |= 1 << uint(v)
+				}
+				if set != wantSet {
+					t.Errorf("%s = %b, want %b", field, set, wantSet)
+				}
+			}
+			check("minute", s.minute, tt.minute)
+			check("hour", s.hour, tt.hour)
+			check("month", s.month, tt.month)
+			check("day of week", s.dow, tt.dow)
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"every minute", "* * * * *", "2026-01-01T10:00:30Z", "2026-01-01T10:01:00Z"},
+		{"strictly after", "0 10 * * *", "2026-01-01T10:00:00Z", "2026-01-02T10:00:00Z"},
+		{"later today", "30 14 * * *", "2026-01-01T10:00:00Z", "2026-01-01T14:30:00Z"},
+		{"step", "*/15 * * * *", "2026-01-01T10:16:00Z", "2026-01-01T10:30:00Z"},
+		{"next hour", "*/15 * * * *", "2026-01-01T10:50:00Z", "2026-01-01T11:00:00Z"},
+		{"month rollover", "0 0 1 * *", "2026-01-31T12:00:00Z", "2026-02-01T00:00:00Z"},
+		{"year rollover", "@yearly", "2026-06-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", "2026-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"31st skips short months", "0 0 31 * *", "2026-04-01T00:00:00Z", "2026-05-31T00:00:00Z"},
+		{"weekdays", "0 9 * * MON-FRI", "2026-01-02T10:00:00Z", "2026-01-05T09:00:00Z"},
+		// 2026-01-03 is a Saturday
+		{"day of week with day of month *", "0 0 * * SAT", "2026-01-01T00:00:00Z", "2026-01-03T00:00:00Z"},
+		// Both restricted: the 15th or any Friday, whichever comes first
+		{"day of month or day of week", "0 0 15 * FRI", "2026-01-03T00:00:00Z", "2026-01-09T00:00:00Z"},
+		{"day of month or day of week (dom first)", "0 0 15 * FRI", "2026-01-13T00:00:00Z", "2026-01-15T00:00:00Z"},
+		{"day of month with day of week *", "0 0 15 * *", "2026-01-03T00:00:00Z", "2026-01-15T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			from, _ := time.Parse(time.RFC3339, tt.from)
+			want, _ := time.Parse(time.RFC3339, tt.want)
+
+			if got := s.Next(from); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got.Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got := s.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %s, want zero time", got)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	lordHowe := mustLoad(t, "Australia/Lord_Howe")
+
+	tests := []struct {
+		name string
+		expr string
+		loc  *time.Location
+		from time.Time
+		want []time.Time // consecutive runs
+	}{
+		{
+			// 2026-03-08: 02:00 EST jumps to 03:00 EDT; 02:00 does not exist
+			name: "gap fires when the gap ends",
+			expr: "0 2 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC), // 03:00 EDT
+				time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC), // 02:00 EDT
+			},
+		},
+		{
+			name: "gap with minutes fires once",
+			expr: "30 2 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC),  // 03:00 EDT
+				time.Date(2026, 3, 9, 6, 30, 0, 0, time.UTC), // 02:30 EDT
+			},
+		},
+		{
+			name: "every 15 minutes across the gap",
+			expr: "*/15 * * * *",
+			loc:  newYork,
+			from: time.Date(2026, 3, 8, 1, 40, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 6, 45, 0, 0, time.UTC), // 01:45 EST
+				time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC),  // 03:00 EDT
+				time.Date(2026, 3, 8, 7, 15, 0, 0, time.UTC), // 03:15 EDT
+			},
+		},
+		{
+			// 2026-11-01: 02:00 EDT falls back to 01:00 EST; 01:xx happens twice
+			name: "repeated hour fires on the first occurrence only",
+			expr: "30 1 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 10, 31, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC), // 01:30 EST next day
+			},
+		},
+		{
+			name: "every 30 minutes across the repeated hour",
+			expr: "*/30 * * * *",
+			loc:  newYork,
+			from: time.Date(2026, 11, 1, 5, 10, 0, 0, time.UTC), // 01:10 EDT
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC),  // 02:00 EST
+			},
+		},
+		{
+			name: "starting inside the second occurrence",
+			expr: "45 1 * * *",
+			loc:  newYork,
+			from: time.Date(2026, 11, 1, 6, 15, 0, 0, time.UTC), // 01:15 EST
+			want: []time.Time{
+				time.Date(2026, 11, 2, 6, 45, 0, 0, time.UTC), // 01:45 EST next day
+			},
+		},
+		{
+			// 2026-10-04: 02:00 +10:30 jumps to 02:30 +11:00
+			name: "half-hour gap",
+			expr: "15 2 * * *",
+			loc:  lordHowe,
+			from: time.Date(2026, 10, 3, 12, 0, 0, 0, lordHowe),
+			want: []time.Time{
+				time.Date(2026, 10, 4, 2, 30, 0, 0, lordHowe),
+			},
+		},
+		{
+			// 2026-04-05: 02:00 +11:00 falls back to 01:30 +10:30
+			name: "half-hour repeated time",
+			expr: "45 1 * * *",
+			loc:  lordHowe,
+			from: time.Date(2026, 4, 4, 12, 0, 0, 0, lordHowe),
+			want: []time.Time{
+				time.Date(2026, 4, 4, 14, 45, 0, 0, time.UTC), // 01:45 +11:00
+				time.Date(2026, 4, 5, 15, 15, 0, 0, time.UTC), // 01:45 +10:30 next day
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+
+			from := tt.from.In(tt.loc)
+			for i, want := range tt.want {
+				got := s.Next(from)
+				if !got.Equal(want) {
+					t.Fatalf("run %d: Next(%s) = %s, want %s", i, from, got, want.In(tt.loc))
+				}
+				if got.Location() != tt.loc {
+					t.Errorf("run %d: location = %s, want %s", i, got.Location(), tt.loc)
+				}
+				from = got
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := NextRun("0 9 * * *", "Asia/Bangkok", from)
+	if err != nil {
+		t.Fatalf("NextRun: %v", err)
+	}
+	if want := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextRun = %s, want %s", got, want)
+	}
+
+	if _, err := NextRun("0 9 * * *", "Mars/Olympus", from); err == nil {
+		t.Error("NextRun with unknown time zone succeeded, want error")
+	}
+	if _, err := NextRun("0 0 30 2 *", "UTC", from); err == nil {
+		t.Error("NextRun with expression that never fires succeeded, want error")
+	}
+}